@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// auditEntry is a line of the --audit-log, one per deleted notification
+type auditEntry struct {
+	Id        string    `json:"id"`
+	Repo      string    `json:"repo"`
+	Title     string    `json:"title"`
+	Url       string    `json:"url"`
+	ThreadUrl string    `json:"thread_url"`
+	Reason    string    `json:"reason"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// auditLog appends entries to a file as JSON lines. Every entry is written
+// straight to the file, so a crash mid-run still leaves a usable log.
+type auditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// audit is nil unless --audit-log was given
+var audit *auditLog
+
+func openAuditLog(path string) (*auditLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{file: file}, nil
+}
+
+func (a *auditLog) record(notification Notification) {
+	if a == nil {
+		return
+	}
+	line, err := json.Marshal(auditEntry{
+		Id:        notification.Id,
+		Repo:      notification.Repository.FullName,
+		Title:     notification.Subject.Title,
+		Url:       notification.Subject.Url,
+		ThreadUrl: notification.Url,
+		Reason:    notification.Reason,
+		DeletedAt: time.Now().UTC(),
+	})
+	if err == nil {
+		a.mu.Lock()
+		_, err = a.file.Write(append(line, '\n'))
+		a.mu.Unlock()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cannot write audit log: %v\n", err)
+	}
+}
+
+func (a *auditLog) close() {
+	if a == nil {
+		return
+	}
+	if err := a.file.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cannot close audit log: %v\n", err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+// filterFlags narrow down which notifications of a repository are affected
+// or protect some of them, with any of them set marking a whole repository
+// read in one go won't do
+var filterFlags = []string{
+	"reason", "read-reasons", "only-mentions", "skip-mentions", "type",
+	"only-state-changes", "skip-state-changes",
+	"older-than", "newer-than", "since", "before", "title-match", "title-not-match",
+	"skip-bots", "skip-closed", "skip-merged", "skip-closed-issues", "skip-read",
+	"skip-unread", "only-archived-repos", "only-bots", "subject-url", "repo-visibility",
+	"repo-language", "exclude-repo", "skip-repos-file", "participating",
+	"protect-recent", "protect-labels", "never-delete-reasons",
+	"limit", "interactive", "max-pages", "halt-after", "resume",
+}
+
+// unreadToMark reports whether --mark-all-read-for-repo with filters marks a
+// notification read: whatever is still unread and passes them, apart from
+// what --skip-bots and the like or --only-bots keep
+func unreadToMark(status NotificationResult) bool {
+	if !status.Notification.Unread || status.FilteredBy != "" {
+		return false
+	}
+	switch {
+	case (status.BotPR || status.CI) && skipPRsFromBots,
+		status.ClosedPR && skipClosedPRs,
+		status.MergedPR && skipMergedPRs,
+		status.ClosedIssue && skipClosedIssues:
+		return false
+	}
+	return !onlyBots || status.BotPR || status.CI
+}
+
+func filtered() bool {
+	for _, name := range filterFlags {
+		if flag.Lookup(name).Changed {
+			return true
+		}
+	}
+	return false
+}
+
+// markAllReadForRepo marks every notification of a repository as read with
+// a single request to the endpoint GitHub offers for that
+func markAllReadForRepo(client restClient, repo string) int {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" {
+		fmt.Fprintf(os.Stderr, "error: expected owner/name, got %q\n", repo)
+		return exitFailed
+	}
+	target := fmt.Sprintf("repos/%s/%s/notifications", owner, name)
+	err := perform(func() error {
+		return send(client, http.MethodPut, target, strings.NewReader(`{}`))
+	}, http.MethodPut, target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: marking notifications of %s as read: %v\n", repo, err)
+		return exitFailed
+	}
+	if dryRun {
+		fmt.Printf("Would mark all notifications of %s as read\n", repo)
+	} else {
+		fmt.Printf("Marked all notifications of %s as read 🎉\n", repo)
+	}
+	return exitOK
+}
+
+// markAllRead marks all notifications up to upTo as read with a single
+// request, for --then-mark-all-read once the run is done with the ones it
+// picked. That covers the whole account, not just the unreadLeft
+// notifications the run saw and kept.
+func markAllRead(client restClient, unreadLeft int, upTo time.Time) error {
+	since := displayTime(upTo.UTC().Format(time.RFC3339))
+	if dryRun {
+		if tableOutput() {
+			fmt.Printf("Would mark %d unread notifications left by this run, and any others up to %s, as read\n", unreadLeft, since)
+		}
+		return nil
+	}
+	body := fmt.Sprintf(`{"last_read_at":%q,"read":true}`, upTo.UTC().Format(time.RFC3339))
+	err := withRetry(func() error {
+		return send(client, http.MethodPut, "notifications", strings.NewReader(body))
+	})
+	if err != nil {
+		return err
+	}
+	if tableOutput() {
+		fmt.Printf("Marked %d unread notifications left by this run, and any others up to %s, as read\n", unreadLeft, since)
+	}
+	return nil
+}
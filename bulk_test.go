@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestMarkRepoReadMarksUnread(t *testing.T) {
+	set(t, &markRepoRead, true)
+	set(t, &markRead, true)
+	set(t, &skipPRsFromBots, true)
+	read := notification("1", "PullRequest", prURL)
+	read.Unread = false
+	tests := []struct {
+		name   string
+		status NotificationResult
+		want   bool
+	}{
+		{"unread open PR by a human", NotificationResult{Notification: notification("1", "PullRequest", prURL)}, true},
+		{"unread closed issue", NotificationResult{Notification: notification("1", "Issue", issueURL), ClosedIssue: true}, true},
+		{"already read", NotificationResult{Notification: read, Read: true, ClosedPR: true}, false},
+		{"filtered", NotificationResult{Notification: notification("1", "PullRequest", prURL), FilteredBy: "--older-than/--newer-than"}, false},
+		{"skipped bot PR", NotificationResult{Notification: notification("1", "PullRequest", prURL), BotPR: true}, false},
+		{"excluded", NotificationResult{Notification: notification("1", "PullRequest", prURL), Excluded: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := decide(tt.status)
+			if status.MarkedRead != tt.want || status.Deleted {
+				t.Errorf("decide = %+v, want marked read %v", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnreadLeftCountsKeptUnread(t *testing.T) {
+	read := notification("2", "Issue", issueURL)
+	read.Unread = false
+	s := new(summary)
+	s.add(NotificationResult{Notification: notification("1", "Issue", issueURL)})
+	s.add(NotificationResult{Notification: read})
+	s.add(NotificationResult{Notification: notification("3", "Issue", issueURL), Deleted: true})
+	s.add(NotificationResult{Notification: notification("4", "Issue", issueURL), Protected: true})
+	if s.unreadLeft != 2 {
+		t.Errorf("unreadLeft = %d, want the 2 unread ones kept", s.unreadLeft)
+	}
+}
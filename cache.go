@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// subjects caches fetched pull requests, issues and repositories by URL.
+// Several notifications often point at the same one, e.g. after force pushes.
+var subjects = subjectCache{}
+
+type subjectCache struct {
+	entries sync.Map
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+// cacheEntry is fetched once, workers asking for it in the meantime wait
+type cacheEntry struct {
+	once  sync.Once
+	value any
+	err   error
+}
+
+// fetchSubject gets the subject behind url into a new T, from the cache if
+// any worker has asked for it before
+func fetchSubject[T any](ctx context.Context, client restClient, url string) (*T, error) {
+	cached, loaded := subjects.entries.LoadOrStore(url, new(cacheEntry))
+	entry := cached.(*cacheEntry)
+	if loaded {
+		subjects.hits.Add(1)
+	} else {
+		subjects.misses.Add(1)
+	}
+	entry.once.Do(func() {
+		subject := new(T)
+		entry.err = withRetry(func() error {
+			return client.DoWithContext(ctx, http.MethodGet, url, nil, subject)
+		})
+		entry.value = subject
+	})
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	return entry.value.(*T), nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+const (
+	maxAttempts  = 4
+	initialDelay = 500 * time.Millisecond
+)
+
+// withRetry calls fn until it succeeds, fails with a permanent error or
+// maxAttempts is reached, doubling the delay between attempts
+func withRetry(fn func() error) error {
+	delay := initialDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == maxAttempts || !transient(err) {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// restClient is the part of go-gh's api.RESTClient used by the workers, so
+// they can be handed a fake one
+type restClient interface {
+	Request(method string, path string, body io.Reader) (*http.Response, error)
+	RequestWithContext(ctx context.Context, method string, path string, body io.Reader) (*http.Response, error)
+	DoWithContext(ctx context.Context, method string, path string, body io.Reader, response interface{}) error
+	Get(path string, response interface{}) error
+	Delete(path string, response interface{}) error
+}
+
+// newClient creates the REST client shared by all workers, talking to
+// --hostname or the default gh host through the rate limiter and asking for
+// --api-version on every request
+func newClient() (*api.RESTClient, error) {
+	return api.NewRESTClient(api.ClientOptions{
+		Host:      hostname,
+		AuthToken: authToken(),
+		Headers:   map[string]string{"X-GitHub-Api-Version": apiVersion},
+		Transport: limiter,
+	})
+}
+
+// newAnonymousClient is newClient without a token, for --from-file runs on
+// machines that aren't logged in. Subjects are then fetched unauthenticated,
+// which GitHub limits to 60 requests an hour.
+func newAnonymousClient() (*api.RESTClient, error) {
+	limiter.next = anonymous{limiter.next}
+	return api.NewRESTClient(api.ClientOptions{
+		Host: hostname,
+		// any token keeps go-gh from looking one up, anonymous drops it again
+		AuthToken: "none",
+		Headers:   map[string]string{"X-GitHub-Api-Version": apiVersion},
+		Transport: limiter,
+	})
+}
+
+// anonymous sends requests without the Authorization header
+type anonymous struct {
+	next http.RoundTripper
+}
+
+func (a anonymous) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Del("Authorization")
+	return a.next.RoundTrip(req)
+}
+
+// notFound reports whether a request failed because there's nothing at the
+// path, e.g. a thread deleted in the meantime
+func notFound(err error) bool {
+	var httpErr *api.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
+
+// proxyTransport is the default transport sending everything through the
+// --proxy at rawURL, instead of the one from HTTPS_PROXY and friends
+func proxyTransport(rawURL string) (http.RoundTripper, error) {
+	proxy, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected http, https or socks5", proxy.Scheme)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+	return transport, nil
+}
+
+var errNotAuthenticated = errors.New("not authenticated, the token was rejected")
+
+// checkAuth makes a cheap request to find out whether the token is accepted
+// at all, before workers fail one by one. Only a 401 counts, since tokens of
+// GitHub Apps and the like may be refused the user for other reasons.
+func checkAuth(client restClient) error {
+	err := withRetry(func() error { return client.Get("user", nil) })
+	var httpErr *api.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusUnauthorized {
+		return errNotAuthenticated
+	}
+	if errors.As(err, &httpErr) {
+		return nil
+	}
+	return err
+}
+
+// loginHint tells how to get a working token for the host in use
+func loginHint() string {
+	if hostname != "" {
+		return fmt.Sprintf("Run `gh auth login --hostname %s`, or pass --token.", hostname)
+	}
+	return "Run `gh auth login`, or pass --token."
+}
+
+// authToken is the token given by --token or GH_NUKE_TOKEN, when empty the
+// client authenticates like gh does
+func authToken() string {
+	if token != "" {
+		return token
+	}
+	return os.Getenv("GH_NUKE_TOKEN")
+}
+
+// checkHost makes sure the API host answers at all before any worker starts.
+// Errors from the API itself, like a missing endpoint, still mean it's there.
+func checkHost(client restClient) error {
+	err := client.Get("meta", nil)
+	var httpErr *api.HTTPError
+	if errors.As(err, &httpErr) {
+		return nil
+	}
+	return err
+}
+
+// transient reports whether err is worth retrying: server side errors,
+// exhausted rate limits and dropped connections are, anything else the API
+// tells us is not
+func transient(err error) bool {
+	var httpErr *api.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || rateLimited(httpErr.StatusCode, httpErr.Headers)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// send issues a request and discards the response. Unlike client.Do it copes
+// with endpoints answering 205 Reset Content with an empty body, such as the
+// one marking a thread as read
+func send(client restClient, method string, path string, body io.Reader) error {
+	response, err := client.Request(method, path, body)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// isRateLimited reports whether err is the API refusing a request because
+// the rate limit is exhausted
+func isRateLimited(err error) bool {
+	var httpErr *api.HTTPError
+	return errors.As(err, &httpErr) && rateLimited(httpErr.StatusCode, httpErr.Headers)
+}
+
+// perform runs a request changing a notification with retries, unless this
+// is a dry run. Then the request is only printed with --verbose.
+func perform(request func() error, method string, target string) error {
+	if dryRun {
+		if verbose {
+			counters.clear()
+			fmt.Fprintf(os.Stderr, "%s %s\n", method, requestPath(target))
+		}
+		return nil
+	}
+	return withRetry(request)
+}
+
+// requestPath strips scheme and host from an API URL
+func requestPath(target string) string {
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Host == "" {
+		return "/" + strings.TrimPrefix(target, "/")
+	}
+	return parsed.RequestURI()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestAnonymousDropsAuthorization(t *testing.T) {
+	var sent http.Header
+	transport := anonymous{roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req.Header
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/cli/cli", nil)
+	req.Header.Set("Authorization", "token none")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent.Get("Authorization"); got != "" {
+		t.Errorf("sent Authorization %q", got)
+	}
+	if sent.Get("X-GitHub-Api-Version") == "" {
+		t.Error("dropped the other headers too")
+	}
+	if req.Header.Get("Authorization") == "" {
+		t.Error("changed the caller's request")
+	}
+}
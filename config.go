@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gh-nuke", "config.yaml")
+}
+
+// applyConfig sets flags from a YAML file mapping flag names to values, e.g.
+//
+//	skip-bots: true
+//	workers: 4
+//	exclude-repo: [myorg/*, "*-archived"]
+//
+// It runs after flag.Parse so it can skip every flag given on the command
+// line; those take precedence over the file. A missing file is only an error
+// when it was asked for explicitly with --config.
+func applyConfig(path string, explicit bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	options := map[string]any{}
+	if err := yaml.Unmarshal(data, &options); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for name, value := range options {
+		option := flag.Lookup(name)
+		if option == nil || name == "config" {
+			return fmt.Errorf("%s: unknown option %q", path, name)
+		}
+		if option.Changed {
+			continue
+		}
+		values, isList := value.([]any)
+		if !isList {
+			values = []any{value}
+		}
+		for _, v := range values {
+			if err := flag.Set(name, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("%s: %s: %w", path, name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/cli/go-gh/v2/pkg/term"
+)
+
+// needsConfirmation reports whether to ask before touching any notification.
+// Without a terminal to ask on, the run proceeds as before.
+func needsConfirmation() bool {
+	return !dryRun && !assumeYes && term.IsTerminal(os.Stdin)
+}
+
+// bufferStatuses waits for the tagging stage to finish so the number of
+// affected notifications is known up front
+func bufferStatuses(statuses <-chan NotificationResult) []NotificationResult {
+	buffered := []NotificationResult{}
+	for status := range statuses {
+		buffered = append(buffered, status)
+	}
+	return buffered
+}
+
+func replay(buffered []NotificationResult) <-chan NotificationResult {
+	statuses := make(chan NotificationResult, len(buffered))
+	for _, status := range buffered {
+		statuses <- status
+	}
+	close(statuses)
+	return statuses
+}
+
+// approve has the buffered notifications picked with --interactive or all
+// of them confirmed, and exits without deleting anything otherwise
+func approve(ctx context.Context, buffered []NotificationResult) {
+	if ctx.Err() != nil {
+		return
+	}
+	approved := false
+	if interactiveEnabled() {
+		approved = selectInteractively(ctx, buffered)
+	} else {
+		approved = confirm(ctx, buffered)
+	}
+	if !approved {
+		fmt.Fprintln(os.Stderr, "Aborted, nothing was deleted")
+		os.Exit(exitFailed)
+	}
+}
+
+// confirm asks whether to go ahead, an interrupt while waiting for the
+// answer counts as no
+func confirm(ctx context.Context, buffered []NotificationResult) bool {
+	plan := newPlanCounts(buffered)
+	affected := plan.affected.Total
+	if affected == 0 {
+		return true
+	}
+
+	fmt.Fprintln(os.Stderr, plan)
+	action := "Delete"
+	if markRead {
+		action = "Mark as read"
+	}
+	fmt.Fprintf(os.Stderr, "%s these %d notifications? [y/N] ", action, affected)
+	answers := make(chan string, 1)
+	go func() {
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answers <- answer
+	}()
+	select {
+	case answer := <-answers:
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true
+		}
+		return false
+	case <-ctx.Done():
+		fmt.Fprintln(os.Stderr)
+		return false
+	}
+}
+
+// planCounts breaks down what a run is about to do by why, so there's no
+// need to scroll through the table before confirming. Dry runs print it once
+// they're done instead.
+type planCounts struct {
+	mu        sync.Mutex
+	affected  tally
+	skipped   int
+	filtered  int
+	excluded  int
+	recent    int
+	protected int
+	overLimit int
+	errored   int
+}
+
+// dryRunPlan counts what a dry run would have done as deleters decide, it's
+// nil unless it's a dry run
+var dryRunPlan *planCounts
+
+// newPlanCounts decides on the buffered notifications in the order they're
+// acted on, so the ones past --limit count as over it
+func newPlanCounts(buffered []NotificationResult) *planCounts {
+	plan := new(planCounts)
+	for _, status := range buffered {
+		decided := decide(status)
+		if (decided.Deleted || decided.MarkedRead) && limit > 0 && plan.affected.Total >= limit {
+			decided.Deleted, decided.MarkedRead = false, false
+			decided.OverLimit = true
+		}
+		plan.add(decided)
+	}
+	return plan
+}
+
+func (p *planCounts) add(decided NotificationResult) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case decided.Deleted || decided.MarkedRead:
+		p.affected.add(decided)
+		return
+	case decided.Err != nil:
+		p.errored++
+	case decided.FilteredBy != "":
+		p.filtered++
+	case decided.OverLimit:
+		p.overLimit++
+	case decided.Excluded:
+		p.excluded++
+	case decided.Recent:
+		p.recent++
+	case decided.Protected:
+		p.protected++
+	}
+	p.skipped++
+}
+
+func (p *planCounts) String() string {
+	will, action := "will", "delete"
+	if dryRun {
+		will = "would"
+	}
+	if markRead {
+		action = "mark as read"
+	}
+	affected := []reasonCount{{"bot", p.affected.Bot}, {"closed", p.affected.Closed}, {"merged", p.affected.Merged}, {"closed issue", p.affected.ClosedIssue}, {"read", p.affected.Read}}
+	skipped := []reasonCount{{"filtered", p.filtered}, {"over the limit", p.overLimit}, {"excluded", p.excluded}, {"recent", p.recent}, {"protected", p.protected}, {"failed", p.errored}}
+	line := fmt.Sprintf("%s %s %d%s; %s skip %d", will, action, p.affected.Total, joinCounts(affected), will, p.skipped)
+	return strings.ToUpper(line[:1]) + line[1:] + joinCounts(skipped)
+}
+
+type reasonCount struct {
+	label string
+	n     int
+}
+
+// joinCounts lists the counts that aren't zero, e.g. ": 3 bot, 1 read"
+func joinCounts(counts []reasonCount) string {
+	var parts []string
+	for _, c := range counts {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.label))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestPlanCountsCapAtLimit(t *testing.T) {
+	set(t, &limit, 2)
+	set(t, &dryRun, false)
+	set(t, &markRead, false)
+	closed := NotificationResult{Notification: notification("1", "Issue", issueURL), ClosedIssue: true}
+	kept := NotificationResult{Notification: notification("2", "Issue", issueURL)}
+	plan := newPlanCounts([]NotificationResult{closed, closed, kept, closed})
+	if plan.affected.Total != 2 || plan.overLimit != 1 || plan.skipped != 2 {
+		t.Fatalf("plan = %+v, want 2 affected and 1 of 2 skipped over the limit", plan)
+	}
+	want := "Will delete 2: 2 closed issue; will skip 2: 1 over the limit"
+	if got := plan.String(); got != want {
+		t.Errorf("plan = %q, want %q", got, want)
+	}
+}
+
+func TestDryRunPlan(t *testing.T) {
+	set(t, &dryRun, true)
+	set(t, &markRead, false)
+	plan := new(planCounts)
+	plan.add(NotificationResult{Deleted: true, BotPR: true})
+	plan.add(NotificationResult{OverLimit: true})
+	var missing *planCounts
+	missing.add(NotificationResult{Deleted: true})
+	want := "Would delete 1: 1 bot; would skip 1: 1 over the limit"
+	if got := plan.String(); got != want {
+		t.Errorf("plan = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rawDump writes notifications to the --dump-raw file exactly as the API
+// sent them, one per line, with every field Notification leaves out
+type rawDump struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// dump is nil unless --dump-raw was given
+var dump *rawDump
+
+func openRawDump(path string) (*rawDump, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &rawDump{file: file}, nil
+}
+
+func (d *rawDump) record(raw json.RawMessage) {
+	if d == nil {
+		return
+	}
+	var line bytes.Buffer
+	err := json.Compact(&line, raw)
+	if err == nil {
+		line.WriteByte('\n')
+		d.mu.Lock()
+		_, err = d.file.Write(line.Bytes())
+		d.mu.Unlock()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cannot write raw dump: %v\n", err)
+	}
+}
+
+func (d *rawDump) close() {
+	if d == nil {
+		return
+	}
+	if err := d.file.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cannot close raw dump: %v\n", err)
+	}
+}
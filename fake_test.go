@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// fakeClient answers requests from canned responses instead of the API.
+// GETs of paths it has no response for fail with 404, anything else
+// succeeds with an empty body unless told otherwise.
+type fakeClient struct {
+	mu        sync.Mutex
+	responses map[string]fakeResponse
+	calls     []string
+}
+
+type fakeResponse struct {
+	status int
+	body   string
+	link   string
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{responses: map[string]fakeResponse{}}
+}
+
+// on sets the response to method and path, e.g. on("GET", "notifications", ...)
+func (f *fakeClient) on(method, path string, response fakeResponse) *fakeClient {
+	if response.status == 0 {
+		response.status = http.StatusOK
+	}
+	f.responses[method+" "+path] = response
+	return f
+}
+
+// called reports how often method and path were requested
+func (f *fakeClient) called(method, path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, call := range f.calls {
+		if call == method+" "+path {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeClient) Request(method, path string, body io.Reader) (*http.Response, error) {
+	return f.RequestWithContext(context.Background(), method, path, body)
+}
+
+func (f *fakeClient) RequestWithContext(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, method+" "+path)
+	response, ok := f.responses[method+" "+path]
+	f.mu.Unlock()
+	if !ok {
+		response = fakeResponse{status: http.StatusNoContent}
+		if method == http.MethodGet {
+			response.status = http.StatusNotFound
+		}
+	}
+
+	header := http.Header{}
+	if response.link != "" {
+		header.Set("Link", response.link)
+	}
+	if response.status >= 400 {
+		requestURL, _ := url.Parse(path)
+		return nil, &api.HTTPError{StatusCode: response.status, Headers: header, RequestURL: requestURL, Message: http.StatusText(response.status)}
+	}
+	return &http.Response{
+		StatusCode: response.status,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewBufferString(response.body)),
+	}, nil
+}
+
+func (f *fakeClient) DoWithContext(ctx context.Context, method, path string, body io.Reader, v interface{}) error {
+	response, err := f.RequestWithContext(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil || len(data) == 0 || v == nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (f *fakeClient) Get(path string, v interface{}) error {
+	return f.DoWithContext(context.Background(), http.MethodGet, path, nil, v)
+}
+
+func (f *fakeClient) Delete(path string, v interface{}) error {
+	return f.DoWithContext(context.Background(), http.MethodDelete, path, nil, v)
+}
+
+// set changes a flag variable for the duration of a test
+func set[T any](t *testing.T, variable *T, value T) {
+	t.Helper()
+	old := *variable
+	*variable = value
+	t.Cleanup(func() { *variable = old })
+}
+
+// notification builds a fixture notification about subjectType at
+// subjectURL, pass an empty url for subjects that come without one
+func notification(id, subjectType, subjectURL string) Notification {
+	n := Notification{
+		Id:         id,
+		ThreadID:   id,
+		Reason:     "subscribed",
+		Unread:     true,
+		UpdatedAt:  "2024-05-01T10:00:00Z",
+		Repository: Repository{FullName: "cli/cli", Url: "https://api.github.com/repos/cli/cli"},
+	}
+	n.Subject.Title = "title " + id
+	n.Subject.Type = subjectType
+	n.Subject.Url = subjectURL
+	return n
+}
+
+// page renders notifications as the API lists them
+func page(t *testing.T, notifications ...Notification) string {
+	t.Helper()
+	raws := make([]map[string]any, len(notifications))
+	for i, n := range notifications {
+		raws[i] = map[string]any{
+			"id":         n.Id,
+			"reason":     n.Reason,
+			"unread":     n.Unread,
+			"updated_at": n.UpdatedAt,
+			"url":        "https://api.github.com/notifications/threads/" + n.Id,
+			"repository": map[string]any{"full_name": n.Repository.FullName, "url": n.Repository.Url},
+			"subject":    map[string]any{"title": n.Subject.Title, "type": n.Subject.Type, "url": n.Subject.Url},
+		}
+	}
+	data, err := json.Marshal(raws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
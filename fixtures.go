@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// fixtureRecorder saves the body of every distinct successful GET to the
+// hidden --save-fixtures directory, one file per path and query. Pages of
+// notifications can be read back with --from-file.
+type fixtureRecorder struct {
+	dir   string
+	next  http.RoundTripper
+	mu    sync.Mutex
+	saved map[string]bool
+}
+
+func newFixtureRecorder(dir string, next http.RoundTripper) (*fixtureRecorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fixtureRecorder{dir: dir, next: next, saved: map[string]bool{}}, nil
+}
+
+func (r *fixtureRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	response, err := r.next.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet || response.StatusCode != http.StatusOK {
+		return response, err
+	}
+
+	name := fixtureName(req.URL)
+	r.mu.Lock()
+	saved := r.saved[name]
+	r.saved[name] = true
+	r.mu.Unlock()
+	if saved {
+		return response, nil
+	}
+
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	if err := os.WriteFile(filepath.Join(r.dir, name), body, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cannot save fixture: %v\n", err)
+	}
+	return response, nil
+}
+
+var unsafeFixtureChars = regexp.MustCompile(`[^A-Za-z0-9._=-]+`)
+
+// fixtureName turns e.g. /repos/cli/cli/pulls/12 into
+// repos_cli_cli_pulls_12.json, with the query if there is one
+func fixtureName(u *url.URL) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(u.Path, "/api/v3"), "/")
+	if u.RawQuery != "" {
+		name += "_" + u.RawQuery
+	}
+	return unsafeFixtureChars.ReplaceAllString(name, "_") + ".json"
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// streamFile emits the notifications of --from-file instead of fetching them,
+// e.g. to try filters on a saved inbox. That's a dry run unless
+// --dry-run=false is given, subjects are still fetched from the API.
+func streamFile(ctx context.Context, path string, notificationsChan chan<- Notification) {
+	defer close(notificationsChan)
+
+	eachInFile(path, func(notification Notification) bool {
+		select {
+		case notificationsChan <- notification:
+			counters.fetched.Add(1)
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// eachInFile calls each for every notification of path in order, until it
+// returns false
+func eachInFile(path string, each func(Notification) bool) {
+	notifications, err := readNotifications(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: reading %s: %v\n", path, err)
+		pageErrors.Add(1)
+		return
+	}
+	for i, notification := range notifications {
+		notification.Seq = int64(i + 1)
+		if !each(notification) {
+			return
+		}
+	}
+}
+
+// readNotifications reads a JSON array of notifications like the API returns,
+// or JSON lines like --dump-raw writes, from path or stdin for -
+func readNotifications(path string) ([]Notification, error) {
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	var notifications []Notification
+	decoder := json.NewDecoder(input)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		raws := []json.RawMessage{raw}
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := json.Unmarshal(raw, &raws); err != nil {
+				return nil, err
+			}
+		}
+		for _, raw := range raws {
+			var notification Notification
+			if err := json.Unmarshal(raw, &notification); err != nil {
+				reportMalformed(path, err)
+				continue
+			}
+			notification.ThreadID = parseThreadID(notification)
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications, nil
+}
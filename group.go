@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// repoSummary counts what happened to the notifications of one repository
+// for --group-by repo
+type repoSummary struct {
+	Type       string `json:"type"`
+	Repo       string `json:"repo"`
+	Seen       int    `json:"seen"`
+	Deleted    int    `json:"deleted"`
+	MarkedRead int    `json:"marked_read"`
+	Skipped    int    `json:"skipped"`
+	Errors     int    `json:"errors"`
+}
+
+func (r *repoSummary) add(result NotificationResult) {
+	r.Seen++
+	switch {
+	case result.Err != nil:
+		r.Errors++
+	case result.Deleted:
+		r.Deleted++
+	case result.MarkedRead:
+		r.MarkedRead++
+	default:
+		r.Skipped++
+	}
+}
+
+func (r *repoSummary) String() string {
+	line := fmt.Sprintf("%s: %d deleted", r.Repo, r.Deleted)
+	if markRead {
+		line += fmt.Sprintf(", %d marked read", r.MarkedRead)
+	}
+	line += fmt.Sprintf(", %d skipped", r.Skipped)
+	if r.Errors > 0 {
+		line += fmt.Sprintf(", %d errors", r.Errors)
+	}
+	return line
+}
+
+// groupByRepo collects all results before printing one line per repository,
+// the ones with most notifications deleted or marked read first
+func groupByRepo(results <-chan NotificationResult, totals *summary) []*repoSummary {
+	byRepo := map[string]*repoSummary{}
+	for result := range results {
+		totals.add(result)
+		name := result.Notification.Repository.FullName
+		group, ok := byRepo[name]
+		if !ok {
+			group = &repoSummary{Type: "repo", Repo: name}
+			byRepo[name] = group
+		}
+		group.add(result)
+	}
+
+	groups := make([]*repoSummary, 0, len(byRepo))
+	for _, group := range byRepo {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i].Deleted+groups[i].MarkedRead, groups[j].Deleted+groups[j].MarkedRead
+		if a != b {
+			return a > b
+		}
+		if groups[i].Seen != groups[j].Seen {
+			return groups[i].Seen > groups[j].Seen
+		}
+		return groups[i].Repo < groups[j].Repo
+	})
+	return groups
+}
+
+func printGroups(results <-chan NotificationResult, totals *summary) {
+	groups := groupByRepo(results, totals)
+	totals.finish()
+
+	switch {
+	case jsonOutput:
+		array := new(jsonArray)
+		for _, group := range groups {
+			array.write(group)
+		}
+		array.write(totals)
+		array.close()
+	case outputFormat == "csv":
+		writer := csv.NewWriter(output)
+		writer.Write([]string{"repo", "seen", "deleted", "marked_read", "skipped", "errors"})
+		for _, group := range groups {
+			writer.Write([]string{
+				group.Repo,
+				strconv.Itoa(group.Seen),
+				strconv.Itoa(group.Deleted),
+				strconv.Itoa(group.MarkedRead),
+				strconv.Itoa(group.Skipped),
+				strconv.Itoa(group.Errors),
+			})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	default:
+		for _, group := range groups {
+			fmt.Fprintln(output, group)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/term"
+)
+
+// interactiveEnabled is --interactive, unless stdin can't answer
+func interactiveEnabled() bool {
+	return interactive && term.IsTerminal(os.Stdin)
+}
+
+// selectInteractively lists the notifications that would be deleted or marked
+// read and lets the user toggle them by number. Those left unselected are
+// kept. It reports false when the user aborts or the run is interrupted.
+func selectInteractively(ctx context.Context, buffered []NotificationResult) bool {
+	var candidates []int
+	for i, status := range buffered {
+		if decided := decide(status); decided.Deleted || decided.MarkedRead {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return true
+	}
+
+	selected := make([]bool, len(candidates))
+	for i := range selected {
+		selected[i] = true
+	}
+	lines := make(chan string)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- line
+		}
+	}()
+
+	for {
+		for n, i := range candidates {
+			box := " "
+			if selected[n] {
+				box = "x"
+			}
+			fmt.Fprintf(os.Stderr, "%3d [%s] %s\n", n+1, box, formatRow(decide(buffered[i])))
+		}
+		fmt.Fprint(os.Stderr, "Toggle by number, e.g. 2 5-7, a for all, n for none, enter to go ahead, q to abort: ")
+
+		var line string
+		select {
+		case answer, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(os.Stderr)
+				return false
+			}
+			line = strings.TrimSpace(answer)
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stderr)
+			return false
+		}
+
+		switch line {
+		case "":
+			for n, i := range candidates {
+				buffered[i].Unselected = !selected[n]
+			}
+			return true
+		case "q":
+			return false
+		case "a", "n":
+			for n := range selected {
+				selected[n] = line == "a"
+			}
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			first, last, err := parseRange(field, len(candidates))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				break
+			}
+			for n := first; n <= last; n++ {
+				selected[n-1] = !selected[n-1]
+			}
+		}
+	}
+}
+
+// parseRange reads a number or a range like 5-7 of the numbers 1 to max
+func parseRange(field string, max int) (int, int, error) {
+	from, to, isRange := strings.Cut(field, "-")
+	first, err := strconv.Atoi(from)
+	last := first
+	if err == nil && isRange {
+		last, err = strconv.Atoi(to)
+	}
+	if err != nil || first < 1 || last > max || first > last {
+		return 0, 0, fmt.Errorf("%q is not a number or range between 1 and %d", field, max)
+	}
+	return first, last, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logWriter sends log lines to stderr, out of the way of the progress counter
+type logWriter struct{}
+
+func (logWriter) Write(p []byte) (int, error) {
+	counters.clear()
+	return os.Stderr.Write(p)
+}
+
+// logDecision explains at debug level why a notification is or isn't deleted
+func logDecision(status NotificationResult) {
+	if !slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	notification := status.Notification
+	attrs := []any{"id", notification.Id, "seq", notification.Seq, "repo", notification.Repository.FullName, "title", notification.Subject.Title}
+	if repoVisibility != "" {
+		attrs = append(attrs, "visibility", visibility(notification.Repository))
+	}
+	switch {
+	case status.Err != nil:
+		slog.Debug("skip: "+status.Err.Error(), attrs...)
+	case status.FilteredBy != "":
+		slog.Debug("skip: not matched by "+status.FilteredBy, attrs...)
+	case status.Excluded:
+		slog.Debug("skip: repository excluded by --exclude-repo", attrs...)
+	case status.Unselected:
+		slog.Debug("skip: not selected with --interactive", attrs...)
+	case status.Recent:
+		slog.Debug("skip: updated within --protect-recent", attrs...)
+	case status.Protected:
+		if status.Labeled {
+			slog.Debug("skip: label protected by --protect-labels", attrs...)
+		} else {
+			slog.Debug("skip: reason protected by --never-delete-reasons", attrs...)
+		}
+	case status.Deleted || status.MarkedRead:
+		slog.Debug("delete: "+strings.Join(causes(status), ", "), attrs...)
+	default:
+		slog.Debug("skip: "+strings.Join(keptBecause(status), ", "), attrs...)
+	}
+}
+
+// causes lists the markers of a notification that make it a candidate for
+// deletion
+func causes(status NotificationResult) []string {
+	var found []string
+	if status.BotPR {
+		found = append(found, "PR from a bot")
+	}
+	if status.CI {
+		found = append(found, "CI notification")
+	}
+	if status.ClosedPR {
+		found = append(found, "closed PR")
+	}
+	if status.MergedPR {
+		found = append(found, "merged PR")
+	}
+	if status.ClosedIssue {
+		found = append(found, "closed issue")
+	}
+	if status.Read {
+		found = append(found, "read")
+	}
+	return found
+}
+
+// keptBecause explains why none of the markers led to deletion
+func keptBecause(status NotificationResult) []string {
+	if onlyBots {
+		return []string{"not a PR from a bot or CI but --only-bots set"}
+	}
+	var why []string
+	if status.BotPR {
+		why = append(why, "PR from a bot but --skip-bots set")
+	}
+	if status.CI {
+		why = append(why, "CI notification but --skip-bots set")
+	}
+	if status.ClosedPR {
+		why = append(why, "closed PR but --skip-closed set")
+	}
+	if status.MergedPR {
+		why = append(why, "merged PR but --skip-merged set")
+	}
+	if status.ClosedIssue {
+		why = append(why, "closed issue but --skip-closed-issues set")
+	}
+	if status.Read {
+		if skipReadNotifications {
+			why = append(why, "read but --skip-read set")
+		} else {
+			why = append(why, "read but reason not in --read-reasons")
+		}
+	}
+	if len(why) == 0 {
+		why = append(why, "nothing to nuke")
+	}
+	return why
+}
@@ -1,33 +1,43 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
-	"regexp"
+	"os/signal"
+	"path"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	flag "github.com/spf13/pflag"
-
-	"github.com/cli/go-gh/v2/pkg/api"
 )
 
 type Notification struct {
 	Id         string
+	ThreadID   string `json:"-"`
 	Reason     string
 	Url        string
 	Unread     bool
 	UpdatedAt  string `json:"updated_at"`
-	Repository struct {
-		FullName string `json:"full_name"`
-	}
-	Subject struct {
+	Repository Repository
+	Subject    struct {
 		Title string
 		Url   string
 		Type  string
+		// HtmlUrl isn't part of notifications, tag fills it in
+		HtmlUrl string `json:"html_url"`
 	}
+	// Seq is the order the notification came in from the stream, from 1
+	Seq int64 `json:"-"`
 }
 
 type NotificationResult struct {
@@ -35,214 +45,1236 @@ type NotificationResult struct {
 	Deleted      bool
 	Read         bool
 	BotPR        bool
+	CI           bool
 	ClosedPR     bool
+	MergedPR     bool
+	ClosedIssue  bool
+	MarkedRead   bool
+	Unsubscribed bool
+	Excluded     bool
+	Recent       bool
+	Protected    bool
+	Labeled      bool
+	Unselected   bool
+	OverLimit    bool
+	ArchivedRepo bool
+	SelectedType bool
+	FilteredBy   string
+	Err          error
 }
 
 type PullRequest struct {
-	State string
-	User  struct{ Type string }
+	State    string
+	Merged   bool
+	MergedAt string `json:"merged_at"`
+	HtmlUrl  string `json:"html_url"`
+	Labels   []Label
+	User     struct {
+		Login string
+		Type  string
+	}
+}
+
+type Issue struct {
+	State   string
+	HtmlUrl string `json:"html_url"`
+	Labels  []Label
+}
+
+type Label struct {
+	Name string
+}
+
+// Repository is the repository of a notification, Archived and Language
+// though are only set when fetched from Url. Private comes with the
+// notification.
+type Repository struct {
+	FullName string `json:"full_name"`
+	Url      string
+	Private  bool
+	Archived bool
+	Language string
 }
 
 const (
-	BotPR    = "🤖"
-	ClosedPR = "✅"
-	Read     = "👓"
-	Deleted  = "❌"
+	BotPR        = "🤖"
+	CI           = "🏗️"
+	ClosedPR     = "✅"
+	MergedPR     = "🔀"
+	ClosedIssue  = "☑️"
+	Read         = "👓"
+	Deleted      = "❌"
+	MarkedRead   = "📖"
+	Unsubscribed = "🔕"
+	Excluded     = "🛡️"
+	Recent       = "🐣"
+	Protected    = "🔒"
+	Error        = "⚠️"
+	OverLimit    = "⏸️"
+	ArchivedRepo = "🗄️"
+	SelectedType = "🎯"
 )
 
+// defaultBotLogins are service accounts not always typed as Bot by the API,
+// --bot-logins adds to them
+var defaultBotLogins = []string{"dependabot", "dependabot-preview", "renovate", "renovate-bot", "github-actions", "snyk-bot"}
+
 var skipPRsFromBots bool
+var onlyBots bool
+var ciAsBot bool
+var botLogins []string
 var skipClosedPRs bool
+var skipMergedPRs bool
+var skipClosedIssues bool
 var skipReadNotifications bool
+var skipUnread bool
+var deleteAllRead bool
 var dryRun bool
+var exitIfPending bool
+var verbose bool
+var markRead bool
+var unsubscribe bool
+var onlyArchivedRepos bool
+var thenMarkAllRead bool
 var numWorkers int
+var minWorkers int
+var maxWorkers int
+var fetchWorkers int
+var maxPages int
+var perPage int
+var deleteWorkers int
 var haltAfter int
+var allPages bool
+var participating bool
+var maxRate int
+var maxRepoRate int
+var limit int
+var resume bool
+var hostname string
+var token string
+var skipAuthCheck bool
+var proxy string
+var apiVersion string
+var configPath string
+var auditLogPath string
+var restorePath string
+var markAllReadRepo string
+
+// markRepoRead is set when filters keep --mark-all-read-for-repo from using
+// its single request, the unread notifications passing them get marked read
+// one by one instead
+var markRepoRead bool
+var planOutPath string
+var outputPath string
+var dumpRawPath string
+var saveFixturesDir string
+var fromFile string
+var metricsPath string
+var groupBy string
+var sortBy string
+var rowTemplate templateValue
+var summaryOnly bool
+var showVersion bool
+var interactive bool
+var showStats bool
+var showSeq bool
+var quiet bool
+var noPager bool
+var failOnError bool
+var watch bool
+var watchInterval time.Duration
+var applyPlanPath string
+var logLevel = levelValue{slog.LevelError}
+var reasons []string
+var readReasons []string
+var neverDeleteReasons []string
+var protectLabels []string
+var onlyMentions bool
+var skipMentions bool
+var onlyStateChanges bool
+var skipStateChanges bool
+var subjectTypes []string
+var repos []string
+var excludeRepos []string
+var repoRegex regexpValue
+var skipReposFile string
+var subjectURL string
+var repoVisibility string
+var repoLanguage string
+var olderThan durationValue
+var protectRecent durationValue
+var newerThan durationValue
+var since timeValue
+var before timeValue
+var titleMatch regexpValue
+var titleNotMatch regexpValue
+var jsonOutput bool
+var noEmoji bool
+var colorMode string
+var timezone string
+var outputFormat string
+var assumeYes bool
 
 func main() {
 	flag.BoolVar(&skipPRsFromBots, "skip-bots", false, "don't delete notifications on PRs from bots")
-	flag.BoolVar(&skipClosedPRs, "skip-closed", false, "don't delete notifications on closed / merged PRs")
+	flag.BoolVar(&ciAsBot, "ci-as-bot", false, "treat CI notifications about check suites like PRs from bots")
+	flag.BoolVar(&onlyBots, "only-bots", false, "only delete notifications about PRs from bots, whether read or not, and keep all others")
+	flag.StringSliceVar(&botLogins, "bot-logins", nil, "additional globs of logins to treat as bots, e.g. my-ci-*")
+	flag.BoolVar(&skipClosedPRs, "skip-closed", false, "don't delete notifications on closed / merged PRs, --skip-merged=false to still delete merged ones")
+	flag.BoolVar(&skipMergedPRs, "skip-merged", false, "don't delete notifications on merged PRs (default --skip-closed)")
+	flag.BoolVar(&skipClosedIssues, "skip-closed-issues", false, "don't delete notifications on closed issues")
 	flag.BoolVar(&skipReadNotifications, "skip-read", false, "don't delete read notifications")
+	flag.BoolVar(&skipUnread, "skip-unread", false, "never delete unread notifications, whatever else applies to them")
+	flag.BoolVar(&deleteAllRead, "delete-all-read", false, "delete every read notification of any type, ignoring --skip-read and --read-reasons")
 	flag.BoolVar(&dryRun, "dry-run", false, "dry run without deleting anything")
-	flag.IntVar(&numWorkers, "workers", runtime.NumCPU(), "number of workers")
-	// TODO get rid of this and store offsets in a file
+	flag.BoolVar(&exitIfPending, "exit-if-pending", false, "with --dry-run, exit with status 4 if any notification would be deleted or marked read")
+	flag.BoolVarP(&verbose, "verbose", "v", false, "with --dry-run, print the API requests that would be made to stderr, with --repo-visibility also whether each repository is public or private")
+	flag.BoolVarP(&assumeYes, "yes", "y", false, "don't ask for confirmation before deleting")
+	flag.IntVar(&limit, "limit", 0, "stop deleting after this many notifications, set to 0 for no limit")
+	flag.BoolVar(&markRead, "mark-read", false, "mark notifications as read instead of deleting them")
+	flag.BoolVar(&unsubscribe, "unsubscribe", false, "also unsubscribe from the threads of deleted notifications to stop future ones")
+	flag.BoolVar(&failOnError, "fail-on-error", false, "stop the run as soon as deleting, marking or unsubscribing fails (default keep going)")
+	flag.BoolVar(&thenMarkAllRead, "then-mark-all-read", false, "once done, mark every notification on the account up to the start of the run as read with a single request, needs --yes")
+	flag.BoolVar(&onlyArchivedRepos, "only-archived-repos", false, "only delete notifications from archived repositories, looking up each repository once")
+	flag.StringSliceVar(&reasons, "reason", nil, "only delete notifications with the given reasons, e.g. ci_activity,subscribed (default all reasons)")
+	flag.BoolVar(&onlyMentions, "only-mentions", false, "only delete notifications about mentions of you or your teams, same as --reason mention,team_mention")
+	flag.BoolVar(&skipMentions, "skip-mentions", false, "don't delete notifications about mentions of you or your teams")
+	flag.BoolVar(&onlyStateChanges, "only-state-changes", false, "only delete notifications about pull requests or issues being opened, closed or reopened, same as --reason state_change")
+	flag.BoolVar(&skipStateChanges, "skip-state-changes", false, "don't delete notifications about pull requests or issues being opened, closed or reopened")
+	flag.StringSliceVar(&neverDeleteReasons, "never-delete-reasons", []string{"security_alert"}, "never delete notifications with the given reasons, whatever else is set; pass an empty value to protect none")
+	flag.StringSliceVar(&protectLabels, "protect-labels", nil, "never delete notifications about pull requests or issues with any of the given labels, e.g. keep,pinned")
+	flag.StringSliceVar(&readReasons, "read-reasons", nil, "only delete read notifications with the given reasons, e.g. ci_activity (default all reasons)")
+	flag.StringSliceVar(&subjectTypes, "type", nil, "only delete notifications about the given subject types, e.g. Release,CheckSuite,Commit,Discussion,PullRequest,Issue (default all types); types other than PullRequest and Issue are then deleted read or not")
+	flag.StringSliceVar(&repos, "repo", nil, "only delete notifications from the given owner/name repositories, can be repeated (default all repositories)")
+	flag.Var(&repoRegex, "repo-regex", "only delete notifications from repositories whose owner/name matches this regular expression, in addition to --repo")
+	flag.StringVar(&repoVisibility, "repo-visibility", "", "only delete notifications from public or private repositories (default both)")
+	flag.StringVar(&repoLanguage, "repo-language", "", "only delete notifications from repositories with this primary language, e.g. Go, fetching every repository once")
+	flag.StringVar(&subjectURL, "subject-url", "", "only delete notifications about this pull request or issue, by its web or API URL, fetching just those of its repository")
+	flag.StringVar(&skipReposFile, "skip-repos-file", "", "never delete notifications from the owner/name repositories listed in this file, one per line, # starts a comment")
+	flag.StringSliceVar(&excludeRepos, "exclude-repo", nil, "never delete notifications from repositories matching the given glob, e.g. myorg/* or *-archived, can be repeated")
+	flag.Var(&protectRecent, "protect-recent", "never delete notifications updated less than this long ago, e.g. 5m (default off)")
+	flag.Var(&olderThan, "older-than", "only delete notifications last updated longer ago than this, e.g. 720h or 30d")
+	flag.Var(&newerThan, "newer-than", "only delete notifications last updated within this window, e.g. 24h or 1d")
+	flag.Var(&since, "since", "only delete notifications last updated at or after this date, e.g. 2024-01-01")
+	flag.Var(&before, "before", "only delete notifications last updated before this date, e.g. 2024-06-01")
+	flag.BoolVar(&participating, "participating", false, "only fetch notifications you're directly participating in or mentioned in")
+	flag.Var(&titleMatch, "title-match", "only delete notifications whose title matches this regular expression")
+	flag.Var(&titleNotMatch, "title-not-match", "only delete notifications whose title doesn't match this regular expression")
+	flag.BoolVar(&jsonOutput, "json", false, "print results as a JSON array")
+	flag.StringVar(&outputFormat, "format", "table", "output format: table or csv")
+	flag.BoolVar(&noEmoji, "no-emoji", false, "print ASCII tags instead of emoji markers")
+	flag.StringVar(&colorMode, "color", "auto", "color the results table: auto, always or never")
+	flag.StringVar(&timezone, "timezone", "", "show times in the table in this time zone, e.g. Europe/Berlin or UTC (default local time)")
+	flag.StringVar(&token, "token", "", "authenticate with this token instead of the one gh uses, GH_NUKE_TOKEN works too")
+	flag.BoolVar(&skipAuthCheck, "skip-auth-check", false, "don't check the token works before starting, e.g. for tokens that can't read the user")
+	flag.StringVar(&proxy, "proxy", "", "send all requests through this proxy, e.g. http://proxy.example.com:3128 (default from HTTPS_PROXY, HTTP_PROXY and NO_PROXY)")
+	flag.StringVar(&apiVersion, "api-version", "2022-11-28", "REST API version to ask for, e.g. an older one a GitHub Enterprise Server supports")
+	flag.StringVar(&hostname, "hostname", "", "GitHub host to talk to, e.g. a GitHub Enterprise Server (default host from gh)")
+	flag.IntVar(&numWorkers, "workers", runtime.NumCPU(), "number of workers, 0 runs everything sequentially in a single goroutine for debugging")
+	flag.IntVar(&minWorkers, "min-workers", 2, "number of workers fetching PRs and issues to start with, more are added while the rate limit allows")
+	flag.IntVar(&maxWorkers, "max-workers", 0, "maximum number of workers fetching PRs and issues, capped by --fetch-workers (default --fetch-workers)")
+	flag.IntVar(&perPage, "per-page", 100, "fetch this many notifications per page, at most 100")
+	flag.IntVar(&maxPages, "max-pages", 0, "stop after fetching this many pages of the newest notifications (default all pages)")
+	flag.IntVar(&fetchWorkers, "fetch-workers", 0, "number of workers fetching PRs and issues (default --workers)")
+	flag.IntVar(&deleteWorkers, "delete-workers", 0, "number of workers deleting notifications, which is cheap on the rate limit (default --workers)")
+	flag.IntVar(&maxRepoRate, "max-repo-rate", 0, "maximum number of notifications of the same repository acted on per minute, against secondary rate limits, set to 0 for no limit")
+	flag.IntVar(&maxRate, "max-rate", 0, "maximum number of API requests per minute, set to 0 for no limit")
+	flag.BoolVar(&resume, "resume", false, "resume an interrupted run where it left off")
 	flag.IntVar(&haltAfter, "halt-after", 50, "stop after a given number of read messages in a row, set to 0 to never stop")
+	flag.BoolVar(&allPages, "all-pages", false, "ignore --halt-after and fetch every page, in parallel; takes longer on big inboxes but finds unread notifications buried behind read ones")
+	flag.Var(&logLevel, "log-level", "what to log to stderr: error, info or debug, which explains every decision")
+	flag.StringVar(&auditLogPath, "audit-log", "", "append a JSON line for every deleted notification to this file")
+	flag.StringVar(&markAllReadRepo, "mark-all-read-for-repo", "", "mark all notifications of an owner/name repository as read with a single request, with other filters the unread ones passing them are marked one by one")
+	flag.BoolVar(&watch, "watch", false, "keep running, nuking new notifications every --interval until interrupted")
+	flag.DurationVar(&watchInterval, "interval", 5*time.Minute, "time between runs with --watch")
+	flag.BoolVarP(&quiet, "quiet", "q", false, "print nothing but errors, --json still prints its output")
+	flag.BoolVar(&noPager, "no-pager", false, "don't page the table through GH_PAGER, the pager of gh or PAGER")
+	flag.BoolVar(&showSeq, "show-seq", false, "prefix every result with the order it was fetched in, to tell how parallel processing reordered them")
+	flag.BoolVar(&showStats, "stats", false, "add counts by age, reason and subject type of all notifications seen to the summary, e.g. to profile an inbox with --dry-run")
+	flag.BoolVarP(&interactive, "interactive", "i", false, "pick which of the matched notifications to delete before anything is deleted")
+	flag.BoolVar(&showVersion, "version", false, "print the version and exit")
+	flag.BoolVar(&summaryOnly, "summary-only", false, "print only the summary instead of every notification, e.g. to preview a big inbox with --dry-run")
+	flag.Var(&rowTemplate, "template", "print every result through this Go text/template instead of the table, e.g. '{{.Notification.Subject.Title}} ({{.Notification.Reason}})'")
+	flag.StringVar(&sortBy, "sort", "", "print results ordered by updated, repo or reason, which holds them all in memory until the run is over (default as they come)")
+	flag.StringVar(&groupBy, "group-by", "", "print counts per group instead of every notification, by: repo")
+	flag.StringVar(&metricsPath, "metrics-file", "", "after every run write counters to this file in the Prometheus text format, e.g. for the textfile collector of node_exporter with --watch")
+	flag.StringVar(&fromFile, "from-file", "", "read notifications from this file, or stdin for -, instead of listing them, as a JSON array or the JSON lines of --dump-raw; implies --dry-run unless --dry-run=false is given")
+	flag.StringVar(&saveFixturesDir, "save-fixtures", "", "save every distinct API response to this directory as JSON files, e.g. for --from-file")
+	flag.CommandLine.MarkHidden("save-fixtures")
+	flag.StringVar(&dumpRawPath, "dump-raw", "", "write every notification to this file as JSON lines, exactly as received from the API")
+	flag.StringVar(&outputPath, "output-file", "", "write the results to this file instead of stdout, in the format chosen by --json or --format")
+	flag.StringVar(&planOutPath, "plan-out", "", "with --dry-run, write the intended actions to this JSON file for review")
+	flag.StringVar(&applyPlanPath, "apply-plan", "", "carry out the actions of a plan written by --plan-out, then exit")
+	flag.StringVar(&restorePath, "restore-from", "", "re-subscribe to the threads in an audit log written by --audit-log, then exit")
+	flag.StringVar(&configPath, "config", defaultConfigPath(), "YAML file with default values for any of these flags")
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "`gh nuke` deletes all GitHub notifications that are from bots,\nand/or are about closed pull requests\n\nUsage:\n")
+		fmt.Fprintf(os.Stderr, "`gh nuke` deletes all GitHub notifications that are from bots,\nand/or are about closed pull requests and issues\n\nUsage:\n")
 		flag.PrintDefaults()
 	}
 	flag.Parse()
+	if showVersion {
+		printVersion()
+		os.Exit(exitOK)
+	}
+	if err := applyConfig(configPath, flag.Lookup("config").Changed); err != nil {
+		fmt.Fprintf(os.Stderr, "error: reading config: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(logWriter{}, &slog.HandlerOptions{Level: logLevel.Level})))
 	args := flag.Args()
 	if len(args) != 0 {
-		flag.Usage()
-		msg := fmt.Sprintf("unexpected arguments: %v", args)
-		panic(msg)
+		usageError("unexpected arguments: %v", args)
+	}
+	if fromFile != "" && !flag.Lookup("dry-run").Changed {
+		// a saved inbox isn't necessarily this account's, so acting on it
+		// takes an explicit --dry-run=false
+		dryRun = true
+	}
+	if outputFormat != "table" && outputFormat != "csv" {
+		usageError("invalid --format %q, expected table or csv", outputFormat)
+	}
+	if sortBy != "" && sortBy != "updated" && sortBy != "repo" && sortBy != "reason" {
+		usageError("invalid --sort %q, expected updated, repo or reason", sortBy)
+	}
+	if jsonOutput {
+		quiet = false
+	}
+	if _, err := time.Parse(time.DateOnly, apiVersion); err != nil {
+		usageError("invalid --api-version %q, expected a date like 2022-11-28", apiVersion)
+	}
+	if rowTemplate.Template != nil && (jsonOutput || outputFormat == "csv") {
+		usageError("--template can't be combined with --json or --format csv")
+	}
+	if summaryOnly && outputFormat == "csv" {
+		usageError("--summary-only works with the table and --json only")
+	}
+	if summaryOnly && groupBy != "" {
+		usageError("--summary-only and --group-by are mutually exclusive")
+	}
+	if groupBy != "" && groupBy != "repo" {
+		usageError("invalid --group-by %q, expected repo", groupBy)
+	}
+	if jsonOutput && outputFormat == "csv" {
+		usageError("--json and --format csv are mutually exclusive")
+	}
+	var err error
+	if colorEnabled, err = useColor(colorMode); err != nil {
+		usageError("%v", err)
+	}
+	if timezone != "" {
+		if displayZone, err = time.LoadLocation(timezone); err != nil {
+			usageError("invalid --timezone: %v", err)
+		}
+	}
+	if (onlyMentions || skipMentions) && len(reasons) > 0 {
+		usageError("--only-mentions and --skip-mentions can't be combined with --reason")
+	}
+	if (onlyStateChanges || skipStateChanges) && len(reasons) > 0 {
+		usageError("--only-state-changes and --skip-state-changes can't be combined with --reason")
+	}
+	if perPage < 1 || perPage > 100 {
+		usageError("--per-page must be between 1 and 100")
+	}
+	if watch && watchInterval <= 0 {
+		usageError("--interval must be positive")
+	}
+	// --skip-closed covered merged PRs before they were told apart, and
+	// still does unless --skip-merged says otherwise
+	if skipClosedPRs && !flag.Lookup("skip-merged").Changed {
+		skipMergedPRs = true
+	}
+	if exitIfPending && !dryRun {
+		usageError("--exit-if-pending needs --dry-run")
+	}
+	if thenMarkAllRead && !assumeYes && !dryRun {
+		usageError("--then-mark-all-read needs --yes")
+	}
+	if markAllReadRepo != "" && (len(repos) > 0 || repoRegex.Regexp != nil) {
+		usageError("--mark-all-read-for-repo can't be combined with --repo or --repo-regex")
+	}
+	if fromFile != "" && watch {
+		usageError("--from-file and --watch are mutually exclusive")
+	}
+	if interactive && watch {
+		usageError("--interactive and --watch are mutually exclusive")
+	}
+	if interactive && !interactiveEnabled() {
+		fmt.Fprintln(os.Stderr, "warning: --interactive needs a terminal, going ahead without")
+	}
+	if watch && needsConfirmation() {
+		usageError("--watch can't ask for confirmation, add --yes or --dry-run")
+	}
+	if onlyBots && skipPRsFromBots {
+		usageError("--only-bots and --skip-bots are mutually exclusive")
+	}
+	if onlyMentions && skipMentions {
+		usageError("--only-mentions and --skip-mentions are mutually exclusive")
+	}
+	if onlyStateChanges && skipStateChanges {
+		usageError("--only-state-changes and --skip-state-changes are mutually exclusive")
+	}
+	if repoVisibility != "" && repoVisibility != "public" && repoVisibility != "private" {
+		usageError("invalid --repo-visibility %q, expected public or private", repoVisibility)
+	}
+	if subjectURL != "" {
+		if wantedSubject, err = subjectPath(subjectURL); err != nil {
+			usageError("invalid --subject-url: %v", err)
+		}
+	}
+	if skipReposFile != "" {
+		skipped, err := readRepoList(skipReposFile)
+		if err != nil {
+			usageError("invalid --skip-repos-file: %v", err)
+		}
+		excludeRepos = append(excludeRepos, skipped...)
+	}
+	for _, pattern := range excludeRepos {
+		if _, err := path.Match(pattern, ""); err != nil {
+			usageError("invalid --exclude-repo pattern %q: %v", pattern, err)
+		}
+	}
+	for _, pattern := range botLogins {
+		if _, err := path.Match(pattern, ""); err != nil {
+			usageError("invalid --bot-logins pattern %q: %v", pattern, err)
+		}
+	}
+
+	if numWorkers < 0 {
+		usageError("--workers can't be negative")
+	}
+	if fetchWorkers <= 0 {
+		fetchWorkers = numWorkers
+	}
+	if deleteWorkers <= 0 {
+		deleteWorkers = numWorkers
+	}
+	paging = usePager()
+
+	if auditLogPath != "" {
+		var err error
+		if audit, err = openAuditLog(auditLogPath); err != nil {
+			fmt.Fprintf(os.Stderr, "error: opening audit log: %v\n", err)
+			os.Exit(exitFailed)
+		}
+	}
+
+	if dumpRawPath != "" {
+		var err error
+		if dump, err = openRawDump(dumpRawPath); err != nil {
+			fmt.Fprintf(os.Stderr, "error: opening raw dump: %v\n", err)
+			os.Exit(exitFailed)
+		}
+	}
+
+	if outputPath != "" {
+		if err := openOutput(outputPath); err != nil {
+			fmt.Fprintf(os.Stderr, "error: opening output file: %v\n", err)
+			os.Exit(exitFailed)
+		}
+	}
+
+	if proxy != "" {
+		if limiter.next, err = proxyTransport(proxy); err != nil {
+			usageError("invalid --proxy: %v", err)
+		}
 	}
 
+	if saveFixturesDir != "" {
+		if limiter.next, err = newFixtureRecorder(saveFixturesDir, limiter.next); err != nil {
+			fmt.Fprintf(os.Stderr, "error: creating fixtures directory: %v\n", err)
+			os.Exit(exitFailed)
+		}
+	}
+
+	client, err := newClient()
+	if err != nil && fromFile != "" {
+		client, err = newAnonymousClient()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n%s\n", err, loginHint())
+		os.Exit(exitFailed)
+	}
+	if hostname != "" {
+		if err := checkHost(client); err != nil {
+			fmt.Fprintf(os.Stderr, "error: cannot reach %s: %v\n", hostname, err)
+			os.Exit(1)
+		}
+	}
+	if !skipAuthCheck && fromFile == "" {
+		if err := checkAuth(client); errors.Is(err, errNotAuthenticated) {
+			fmt.Fprintf(os.Stderr, "error: %v\n%s\n", err, loginHint())
+			os.Exit(exitFailed)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(exitFailed)
+		}
+	}
+
+	if maxRate > 0 {
+		limiter.interval = time.Minute / time.Duration(maxRate)
+	}
+	if maxRepoRate > 0 {
+		repoLimiter.interval = time.Minute / time.Duration(maxRepoRate)
+	}
+
+	if restorePath != "" {
+		os.Exit(restoreFrom(client, restorePath))
+	}
+	if applyPlanPath != "" {
+		os.Exit(applyPlan(client, applyPlanPath))
+	}
+	if planOutPath != "" {
+		if !dryRun {
+			fmt.Fprintln(os.Stderr, "error: --plan-out requires --dry-run")
+			os.Exit(exitFailed)
+		}
+		planned = new(planRecorder)
+	}
+	if markAllReadRepo != "" {
+		if !filtered() {
+			os.Exit(markAllReadForRepo(client, markAllReadRepo))
+		}
+		repos = []string{markAllReadRepo}
+		markRepoRead = true
+		markRead = true
+	}
+
+	var saved state
+	if resume {
+		if saved, err = loadState(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: reading resume state: %v\n", err)
+			os.Exit(exitFailed)
+		}
+	}
+	query := notificationsQuery(saved)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if watch {
+		os.Exit(watchNotifications(ctx, stop, client, query))
+	}
+
+	totals := run(ctx, client, query)
+	closeOutput(totals)
+	audit.close()
+	dump.close()
+	if ctx.Err() != nil {
+		if tableOutput() {
+			fmt.Println("Interrupted 🛑")
+			totals.print()
+		}
+		os.Exit(exitInterrupted)
+	}
+	if tableOutput() {
+		fmt.Println("Done 🎉")
+		totals.print()
+	}
+	os.Exit(totals.exitCode())
+}
+
+// usageError reports an invalid combination or value of flags along with the
+// usage, and exits
+func usageError(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+	flag.Usage()
+	os.Exit(exitFailed)
+}
+
+// resultsBuffer is how many results can wait to be printed before delete
+// workers have to, so a slow terminal or pager doesn't hold them up. Deletes
+// take much longer than printing a line, so a buffer the size of a few pages
+// is enough to never block them; the results themselves are small.
+const resultsBuffer = 4096
+
+// run takes notifications matching query through the whole pipeline, from
+// fetching over tagging to deleting, and prints the results
+func run(ctx context.Context, client restClient, query url.Values) *summary {
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	started := time.Now()
+	if dryRun {
+		dryRunPlan = new(planCounts)
+	}
+
+	if tableOutput() && fromFile == "" {
+		if total, ok := countNotifications(ctx, client, query); ok {
+			counters.total.Store(int64(total))
+			fmt.Fprintf(os.Stderr, "Processing ~%d notifications\n", total)
+		}
+	}
+
+	// Without workers there are none for printing to hold up
+	var results chan NotificationResult
+	stopProgress := func() {}
+	if sequential() {
+		results = make(chan NotificationResult)
+		runSequentially(ctx, abort, client, query, results)
+	} else {
+		results = make(chan NotificationResult, resultsBuffer)
+		stopProgress = runConcurrently(ctx, abort, client, query, results)
+	}
+
+	totals := printResults(results)
+	stopProgress()
+	if dryRunPlan != nil {
+		fmt.Fprintln(os.Stderr, dryRunPlan)
+	}
+	if errors.Is(context.Cause(ctx), errFailOnError) {
+		fmt.Fprintf(os.Stderr, "error: %v\n", errFailOnError)
+	}
+	if thenMarkAllRead && ctx.Err() == nil {
+		if err := markAllRead(client, totals.unreadLeft, started); err != nil {
+			fmt.Fprintf(os.Stderr, "error: marking all notifications as read: %v\n", err)
+			totals.Errors++
+		}
+	}
+	if metricsPath != "" {
+		if err := recordMetrics(metricsPath, totals); err != nil {
+			fmt.Fprintf(os.Stderr, "error: writing metrics: %v\n", err)
+		}
+	}
+	if planned != nil {
+		if err := planned.write(planOutPath); err != nil {
+			fmt.Fprintf(os.Stderr, "error: writing plan: %v\n", err)
+			totals.Errors++
+		}
+	}
+	// Everything fetched was handled only if the run wasn't cut short
+	if !dryRun {
+		if ctx.Err() == nil && streamCompleted.Load() {
+			clearState()
+		} else {
+			checkpoints.save()
+		}
+	}
+	return totals
+}
+
+// runConcurrently runs every stage of the pipeline in workers of its own,
+// connected by channels, and returns how to stop the progress line
+func runConcurrently(ctx context.Context, abort context.CancelCauseFunc, client restClient, query url.Values, results chan<- NotificationResult) (stopProgress func()) {
 	notifications := make(chan Notification, numWorkers)
-	statuses := make(chan NotificationResult, numWorkers)
-	results := make(chan NotificationResult, numWorkers)
+	tagged := make(chan NotificationResult, numWorkers)
+	var statuses <-chan NotificationResult = tagged
 
-	go streamNotifications(notifications)
+	pool := newWorkerPool(minWorkers, maxWorkers)
+	go func() {
+		if fromFile != "" {
+			streamFile(ctx, fromFile, notifications)
+		} else {
+			streamNotifications(ctx, client, notificationsPath()+"?"+query.Encode(), notifications)
+		}
+		pool.release()
+	}()
 
 	wg_fetcher := new(sync.WaitGroup)
-	wg_fetcher.Add(numWorkers)
+	wg_fetcher.Add(pool.size())
 	wg_deleter := new(sync.WaitGroup)
-	wg_deleter.Add(numWorkers)
+	wg_deleter.Add(deleteWorkers)
 
-	for i := 0; i < numWorkers; i++ {
-		go tagNotifications(notifications, statuses, wg_fetcher)
-		go deleteNotifications(statuses, results, wg_deleter)
+	for i := 0; i < pool.size(); i++ {
+		go tagNotifications(ctx, client, pool, i, notifications, tagged, wg_fetcher)
 	}
+	tagDone := make(chan struct{})
+	go pool.scale(ctx, tagDone)
+	go func() { wg_fetcher.Wait(); close(tagged); close(tagDone) }()
 
-	go func() { wg_fetcher.Wait(); close(statuses) }()
+	stopProgress = counters.start()
+	if interactiveEnabled() || needsConfirmation() {
+		buffered := bufferStatuses(statuses)
+		stopProgress()
+		approve(ctx, buffered)
+		statuses = replay(buffered)
+		stopProgress = counters.start()
+	}
+
+	for i := 0; i < deleteWorkers; i++ {
+		go deleteNotifications(ctx, abort, client, statuses, results, wg_deleter)
+	}
 	go func() { wg_deleter.Wait(); close(results) }()
+	return stopProgress
+}
 
-	printResults(results)
-	fmt.Println("Done 🎉")
+func tagNotifications(ctx context.Context, client restClient, pool *workerPool, index int, notifications <-chan Notification, statuses chan<- NotificationResult, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for pool.wait(ctx, index) {
+		notification, ok := <-notifications
+		if !ok {
+			return
+		}
+		result := tag(ctx, client, notification)
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case statuses <- result:
+			counters.tagged.Add(1)
+		case <-ctx.Done():
+			return
+		}
+	}
 }
 
-func streamNotifications(notificationsChan chan<- Notification) {
-	defer close(notificationsChan)
-	requestPath := "notifications?all=true"
-	page := 1
-	client, err := api.DefaultRESTClient()
-	if err != nil {
-		panic(err)
+func tag(ctx context.Context, client restClient, notification Notification) NotificationResult {
+	result := NotificationResult{Notification: notification}
+
+	if result.FilteredBy = filteredBy(notification); result.FilteredBy != "" {
+		return result
 	}
 
-	readStreak := 0
-	for {
-		response, err := client.Request(http.MethodGet, requestPath, nil)
-		notifications := []Notification{}
-		decoder := json.NewDecoder(response.Body)
-		err = decoder.Decode(&notifications)
+	result.Excluded = excludedRepo(notification.Repository.FullName)
+	result.Recent = recent(notification, time.Now())
+
+	result.Read = read(notification)
+	result.Notification.Subject.HtmlUrl = webURL(notification.Subject.Url)
+	result.CI = ciAsBot && notification.Subject.Type == "CheckSuite"
+	result.SelectedType = selectedType(notification)
+
+	// Whether a repository is archived and its language take a request of
+	// their own, cached per repository, so they're only looked up for
+	// --repo-language and --only-archived-repos. Notifications from archived
+	// repositories then get deleted as they are, unless the subject turns out
+	// to be protected, e.g. by a label.
+	if repoLanguage != "" || onlyArchivedRepos {
+		if notification.Repository.Url == "" {
+			result.Err = errors.New("fetching repository: no repository url")
+			return result
+		}
+		repo, err := fetchSubject[Repository](ctx, client, notification.Repository.Url)
 		if err != nil {
-			panic(err)
-		}
-		if err := response.Body.Close(); err != nil {
-			fmt.Println(err)
-		}
-		for _, notification := range notifications {
-			if notification.Unread {
-				readStreak = 0
-			} else {
-				readStreak++
-				if haltAfter > 0 && readStreak >= haltAfter {
-					return
-				}
+			result.Err = fmt.Errorf("fetching repository: %w", err)
+			return result
+		}
+		if repoLanguage != "" && !strings.EqualFold(repo.Language, repoLanguage) {
+			result.FilteredBy = "--repo-language"
+			return result
+		}
+		if onlyArchivedRepos {
+			if !repo.Archived {
+				result.FilteredBy = "--only-archived-repos"
+				return result
 			}
-			notificationsChan <- notification
+			result.ArchivedRepo = true
 		}
+	}
+
+	// Discussions, some releases and the like come without a subject url,
+	// there's nothing to fetch so only being read can make them useless
+	if notification.Subject.Url == "" {
+		return result
+	}
 
-		var hasNextPage bool
-		if requestPath, hasNextPage = findNextPage(response); !hasNextPage {
-			break
+	// Labeled means one of --protect-labels, which keeps it like a reason of
+	// --never-delete-reasons would
+	if notification.Subject.Type == "PullRequest" {
+		pr, err := fetchSubject[PullRequest](ctx, client, notification.Subject.Url)
+		if err != nil {
+			result.Err = fmt.Errorf("fetching pull request: %w", err)
+			return result
 		}
-		page++
+		if pr.HtmlUrl != "" {
+			result.Notification.Subject.HtmlUrl = pr.HtmlUrl
+		}
+		result.Labeled = hasProtectedLabel(pr.Labels)
+		result.BotPR = from_a_bot(pr)
+		result.MergedPR = merged(pr)
+		result.ClosedPR = closedPR(pr) && !result.MergedPR
+	} else if notification.Subject.Type == "Issue" {
+		issue, err := fetchSubject[Issue](ctx, client, notification.Subject.Url)
+		if err != nil {
+			result.Err = fmt.Errorf("fetching issue: %w", err)
+			return result
+		}
+		if issue.HtmlUrl != "" {
+			result.Notification.Subject.HtmlUrl = issue.HtmlUrl
+		}
+		result.Labeled = hasProtectedLabel(issue.Labels)
+		result.ClosedIssue = closedIssue(issue)
 	}
+	return result
 }
 
-var linkRE = regexp.MustCompile(`<([^>]+)>;\s*rel="([^"]+)"`)
+// filteredBy names the first filter a notification doesn't pass, so it isn't
+// considered for deletion at all, or is empty when it passes all of them
+func filteredBy(notification Notification) string {
+	switch {
+	case skipUnread && !read(notification):
+		return "--skip-unread"
+	case !hasReason(notification):
+		return "--reason"
+	case !mentionsMatch(notification):
+		return "--only-mentions/--skip-mentions"
+	case !stateChangesMatch(notification):
+		return "--only-state-changes/--skip-state-changes"
+	case !hasSubjectType(notification):
+		return "--type"
+	case !inRepos(notification):
+		return "--repo/--repo-regex"
+	case !inAgeWindow(notification, time.Now()):
+		return "--older-than/--newer-than"
+	case !inDateRange(notification):
+		return "--since/--before"
+	case !titleMatches(notification):
+		return "--title-match/--title-not-match"
+	case !matchesSubject(notification):
+		return "--subject-url"
+	case !hasVisibility(notification):
+		return "--repo-visibility"
+	}
+	return ""
+}
 
-func findNextPage(response *http.Response) (string, bool) {
-	for _, m := range linkRE.FindAllStringSubmatch(response.Header.Get("Link"), -1) {
-		if len(m) > 2 && m[2] == "next" {
-			return m[1], true
+// hasAnyReason reports whether the reason of a notification is one of
+// reasons. Every flag about reasons goes through it, so they all ignore case
+// the same way.
+func hasAnyReason(notification Notification, reasons ...string) bool {
+	for _, reason := range reasons {
+		if strings.EqualFold(notification.Reason, strings.TrimSpace(reason)) {
+			return true
 		}
 	}
-	return "", false
+	return false
 }
 
-func tagNotifications(notifications <-chan Notification, statuses chan<- NotificationResult, wg *sync.WaitGroup) {
-	defer wg.Done()
+func hasReason(notification Notification) bool {
+	return len(reasons) == 0 || hasAnyReason(notification, reasons...)
+}
 
-	client, err := api.DefaultRESTClient()
-	if err != nil {
-		panic(err)
+func mentionsMatch(notification Notification) bool {
+	mention := hasAnyReason(notification, "mention", "team_mention")
+	switch {
+	case onlyMentions:
+		return mention
+	case skipMentions:
+		return !mention
 	}
-	for notification := range notifications {
-		result := NotificationResult{Notification: notification}
+	return true
+}
+
+func stateChangesMatch(notification Notification) bool {
+	stateChange := hasAnyReason(notification, "state_change")
+	switch {
+	case onlyStateChanges:
+		return stateChange
+	case skipStateChanges:
+		return !stateChange
+	}
+	return true
+}
 
-		if !notification.Unread && !skipReadNotifications {
-			result.Read = true
+func hasSubjectType(notification Notification) bool {
+	if len(subjectTypes) == 0 {
+		return true
+	}
+	for _, subjectType := range subjectTypes {
+		if strings.EqualFold(notification.Subject.Type, subjectType) {
+			return true
 		}
+	}
+	return false
+}
 
-		if notification.Subject.Type == "PullRequest" {
+// selectedType reports whether --type asks for a notification by its type
+// alone. Only pull requests and issues have a subject to tell useless ones
+// apart by, so they still need a reason of their own.
+func selectedType(notification Notification) bool {
+	if len(subjectTypes) == 0 {
+		return false
+	}
+	switch notification.Subject.Type {
+	case "PullRequest", "Issue":
+		return false
+	}
+	return hasSubjectType(notification)
+}
+
+// protectedReason reports whether --never-delete-reasons lists the reason of
+// a notification
+func protectedReason(notification Notification) bool {
+	return hasAnyReason(notification, neverDeleteReasons...)
+}
 
-			pr := new(PullRequest)
-			err := client.Get(notification.Subject.Url, &pr)
-			if err != nil {
-				panic(err)
+// hasProtectedLabel reports whether any of labels is one of --protect-labels,
+// ignoring case like GitHub does
+func hasProtectedLabel(labels []Label) bool {
+	for _, label := range labels {
+		for _, protected := range protectLabels {
+			if strings.EqualFold(label.Name, protected) {
+				return true
 			}
-			result.BotPR = from_a_bot(pr)
-			result.ClosedPR = closedPR(pr)
 		}
-		statuses <- result
 	}
+	return false
 }
 
+func hasReadReason(notification Notification) bool {
+	return len(readReasons) == 0 || hasAnyReason(notification, readReasons...)
+}
+
+// visibility is public or private, as for --repo-visibility
+func visibility(repo Repository) string {
+	if repo.Private {
+		return "private"
+	}
+	return "public"
+}
+
+// classified holds the repositories whose visibility was already reported
+// with --verbose, so each is only reported once
+var classified sync.Map
+
+func hasVisibility(notification Notification) bool {
+	if repoVisibility == "" {
+		return true
+	}
+	repo := notification.Repository
+	if _, seen := classified.LoadOrStore(repo.FullName, true); !seen && verbose {
+		fmt.Fprintf(os.Stderr, "%s is %s\n", repo.FullName, visibility(repo))
+	}
+	return visibility(repo) == repoVisibility
+}
+
+// inRepos reports whether a notification is from a repository given by
+// --repo or matching --repo-regex, with neither set all repositories are in
+func inRepos(notification Notification) bool {
+	if len(repos) == 0 && repoRegex.Regexp == nil {
+		return true
+	}
+	for _, repo := range repos {
+		if strings.EqualFold(notification.Repository.FullName, repo) {
+			return true
+		}
+	}
+	return repoRegex.Regexp != nil && repoRegex.MatchString(notification.Repository.FullName)
+}
+
+// inAgeWindow reports whether the age of a notification at now lies within
+// the inclusive window given by --older-than and --newer-than
+func inAgeWindow(notification Notification, now time.Time) bool {
+	if olderThan == 0 && newerThan == 0 {
+		return true
+	}
+	updated, ok := updatedAt(notification)
+	if !ok {
+		return false
+	}
+	age := now.Sub(updated)
+	if olderThan != 0 && age < time.Duration(olderThan) {
+		return false
+	}
+	if newerThan != 0 && age > time.Duration(newerThan) {
+		return false
+	}
+	return true
+}
+
+func inDateRange(notification Notification) bool {
+	if since.IsZero() && before.IsZero() {
+		return true
+	}
+	updated, ok := updatedAt(notification)
+	if !ok {
+		return false
+	}
+	if !since.IsZero() && updated.Before(since.Time) {
+		return false
+	}
+	if !before.IsZero() && !updated.Before(before.Time) {
+		return false
+	}
+	return true
+}
+
+// updatedAt parses the update time of a notification, warning about and
+// rejecting malformed ones
+func updatedAt(notification Notification) (time.Time, bool) {
+	updated, err := time.Parse(time.RFC3339, notification.UpdatedAt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cannot parse updated_at %q of notification %s: %v\n", notification.UpdatedAt, notification.Id, err)
+		return time.Time{}, false
+	}
+	return updated, true
+}
+
+func titleMatches(notification Notification) bool {
+	title := notification.Subject.Title
+	if titleMatch.Regexp != nil && !titleMatch.MatchString(title) {
+		return false
+	}
+	if titleNotMatch.Regexp != nil && titleNotMatch.MatchString(title) {
+		return false
+	}
+	return true
+}
+
+// read notifications are deleted as far as deletesRead allows, unread ones
+// only for their subject, and with --skip-unread not at all
 func read(notification Notification) bool {
 	return !notification.Unread
 }
+
 func from_a_bot(pullRequest *PullRequest) bool {
-	return pullRequest.User.Type == "Bot"
+	return pullRequest.User.Type == "Bot" || botLogin(pullRequest.User.Login)
+}
+
+// botLogin reports whether a login belongs to a bot, going by the [bot]
+// suffix of GitHub apps, the well known service accounts and --bot-logins
+func botLogin(login string) bool {
+	login = strings.ToLower(login)
+	if strings.HasSuffix(login, "[bot]") {
+		return true
+	}
+	for _, patterns := range [][]string{defaultBotLogins, botLogins} {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(strings.ToLower(pattern), login); matched {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func closedPR(pullRequest *PullRequest) bool {
 	return pullRequest.State == "closed"
 }
 
-func deleteNotifications(statuses <-chan NotificationResult, results chan<- NotificationResult, wg *sync.WaitGroup) {
-	defer wg.Done()
-	client, err := api.DefaultRESTClient()
-	if err != nil {
-		panic(err)
+func merged(pullRequest *PullRequest) bool {
+	return pullRequest.Merged || pullRequest.MergedAt != ""
+}
+
+func closedIssue(issue *Issue) bool {
+	return issue.State == "closed"
+}
+
+// recent reports whether a notification was updated within --protect-recent
+// of now, so it may be about something being worked on right now
+func recent(notification Notification, now time.Time) bool {
+	if protectRecent == 0 {
+		return false
 	}
+	updated, ok := updatedAt(notification)
+	return ok && now.Sub(updated) < time.Duration(protectRecent)
+}
 
-	for status := range statuses {
-		if status.BotPR && !skipPRsFromBots {
-			status.Deleted = true
+// excludedRepo reports whether a repository matches any --exclude-repo glob.
+// Patterns without a slash are matched against the repository name only, so
+// *-archived protects owner/foo-archived.
+func excludedRepo(fullName string) bool {
+	fullName = strings.ToLower(fullName)
+	_, name, _ := strings.Cut(fullName, "/")
+	for _, pattern := range excludeRepos {
+		pattern = strings.ToLower(pattern)
+		target := fullName
+		if !strings.Contains(pattern, "/") {
+			target = name
 		}
-		if status.ClosedPR && !skipClosedPRs {
-			status.Deleted = true
-		}
-		if status.Read && !skipReadNotifications {
-			status.Deleted = true
+		if matched, _ := path.Match(pattern, target); matched {
+			return true
 		}
+	}
+	return false
+}
 
-		if status.Deleted && !dryRun {
-			err := client.Delete(status.Notification.Url, nil)
-			if err != nil {
-				panic(err)
-			}
-		}
-		results <- status
+// parseThreadID takes the thread id from the notification id, which is the
+// same thing, falling back to the end of the thread URL in case the id ever
+// stops being numeric
+func parseThreadID(notification Notification) string {
+	if _, err := strconv.ParseUint(notification.Id, 10, 64); err == nil {
+		return notification.Id
+	}
+	if i := strings.LastIndex(notification.Url, "/threads/"); i >= 0 {
+		return notification.Url[i+len("/threads/"):]
 	}
+	return notification.Id
 }
 
-func printResults(results <-chan NotificationResult) {
-	fmt.Println("Time                \tReason [Repo] Title")
+// threadPath is the API path of the thread a notification belongs to
+func threadPath(notification Notification) string {
+	return "notifications/threads/" + notification.ThreadID
+}
 
-	for result := range results {
-		reason := ""
-		if result.Deleted {
-			reason += Deleted
+// deletesRead reports whether being read is reason enough to delete a
+// notification, whatever its subject type:
+//
+//	--delete-all-read  --skip-read  --read-reasons   deleted when read
+//	yes                any          any              always
+//	no                 yes          any              never
+//	no                 no           unset            always
+//	no                 no           set              if the reason is listed
+func deletesRead(notification Notification) bool {
+	if deleteAllRead {
+		return true
+	}
+	return !skipReadNotifications && hasReadReason(notification)
+}
+
+// decide works out what should happen to a tagged notification, without
+// touching the API yet
+func decide(status NotificationResult) NotificationResult {
+	if status.Err != nil {
+		return status
+	}
+	if (status.BotPR || status.CI) && !skipPRsFromBots {
+		status.Deleted = true
+	}
+	if status.ClosedPR && !skipClosedPRs {
+		status.Deleted = true
+	}
+	if status.MergedPR && !skipMergedPRs {
+		status.Deleted = true
+	}
+	if status.ClosedIssue && !skipClosedIssues {
+		status.Deleted = true
+	}
+	if status.Read && deletesRead(status.Notification) {
+		status.Deleted = true
+	}
+	if status.ArchivedRepo || status.SelectedType {
+		status.Deleted = true
+	}
+	// A PR from a bot is the one reason left with --only-bots, read or not
+	if onlyBots {
+		status.Deleted = status.BotPR || status.CI
+	}
+	if markRepoRead {
+		status.Deleted = unreadToMark(status)
+	}
+
+	if status.Excluded || status.Unselected {
+		status.Deleted = false
+	}
+	// Only reported as recent or protected when that's what keeps it
+	status.Recent = status.Recent && status.Deleted
+	if status.Recent {
+		status.Deleted = false
+	}
+	status.Protected = status.Deleted && (protectedReason(status.Notification) || status.Labeled)
+	if status.Protected {
+		status.Deleted = false
+	}
+
+	if status.Deleted && markRead {
+		status.Deleted = false
+		status.MarkedRead = true
+	}
+
+	if unsubscribe && (status.Deleted || status.MarkedRead) {
+		status.Unsubscribed = true
+	}
+	return status
+}
+
+// act makes the requests for everything decided for a notification, in dry
+// runs only pretending to. Failed actions are unset and the error recorded.
+func act(client restClient, status NotificationResult) NotificationResult {
+	if !dryRun && (status.Unsubscribed || status.MarkedRead || status.Deleted) {
+		repoLimiter.wait(strings.ToLower(status.Notification.Repository.FullName))
+	}
+	if status.Unsubscribed {
+		subscription := threadPath(status.Notification) + "/subscription"
+		err := perform(func() error {
+			return send(client, http.MethodPut, subscription, strings.NewReader(`{"ignored":true}`))
+		}, http.MethodPut, subscription)
+		if err != nil {
+			status.Unsubscribed = false
+			status.Err = fmt.Errorf("unsubscribing: %w", err)
 		}
-		if result.Read {
-			reason += Read
+	}
+	if status.MarkedRead {
+		err := perform(func() error {
+			return send(client, http.MethodPatch, threadPath(status.Notification), nil)
+		}, http.MethodPatch, threadPath(status.Notification))
+		if err != nil {
+			status.MarkedRead = false
+			status.Err = fmt.Errorf("marking as read: %w", err)
 		}
-		if result.ClosedPR {
-			reason += ClosedPR
+	}
+	// Deleting a thread is what the web UI calls marking it as done, it
+	// leaves the inbox but can still be found under Done
+	if status.Deleted {
+		err := perform(func() error {
+			return client.Delete(threadPath(status.Notification), nil)
+		}, http.MethodDelete, threadPath(status.Notification))
+		// Already gone, e.g. deleted by the run that's being resumed
+		if notFound(err) {
+			slog.Debug("thread already deleted", "id", status.Notification.Id, "seq", status.Notification.Seq)
+			err = nil
 		}
-		if result.BotPR {
-			reason += BotPR
+		if err != nil {
+			status.Deleted = false
+			status.Err = fmt.Errorf("deleting: %w", err)
 		}
+	}
+	return status
+}
+
+// deletions counts notifications deleted or marked read so far, for --limit
+var deletions atomic.Int64
 
-		if reason != "" {
-			reason += " "
+// reserveDeletion takes one of the deletions allowed by --limit, the caller
+// gives it back if the deletion fails
+func reserveDeletion() bool {
+	if limit <= 0 {
+		return true
+	}
+	if deletions.Add(1) > int64(limit) {
+		deletions.Add(-1)
+		return false
+	}
+	return true
+}
+
+// errFailOnError cancels a run with --fail-on-error once an action failed
+var errFailOnError = errors.New("stopped at the first failure because of --fail-on-error")
+
+// deleteNotifications acts on tagged notifications until they run out or ctx
+// is cancelled. Requests already under way are finished either way, so no
+// thread is left half unsubscribed and deleted.
+func deleteNotifications(ctx context.Context, abort context.CancelCauseFunc, client restClient, statuses <-chan NotificationResult, results chan<- NotificationResult, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for status := range statuses {
+		if ctx.Err() != nil || !handle(abort, client, status, results) {
+			return
 		}
+	}
+}
+
+// handle decides on a tagged notification, acts on it and passes it on to
+// results, reporting false once --fail-on-error stops the run
+func handle(abort context.CancelCauseFunc, client restClient, status NotificationResult, results chan<- NotificationResult) bool {
+	status = decide(status)
+	logDecision(status)
+
+	reserved := status.Deleted || status.MarkedRead
+	if reserved && !reserveDeletion() {
+		reserved = false
+		status.Deleted, status.MarkedRead, status.Unsubscribed = false, false, false
+		status.OverLimit = true
+	}
 
-		fmt.Printf("%s\t%s[%s] %s\n", result.Notification.UpdatedAt, reason, result.Notification.Repository.FullName, result.Notification.Subject.Title)
+	tagged := status.Err == nil
+	status = act(client, status)
+	planned.record(status)
+	dryRunPlan.add(status)
+	if status.Deleted {
+		counters.deleted.Add(1)
+		if !dryRun {
+			audit.record(status.Notification)
+		}
+	}
+	if reserved && !status.Deleted && !status.MarkedRead {
+		deletions.Add(-1)
+	}
+	results <- status
+	checkpoints.handled(status.Notification)
+	if failOnError && tagged && status.Err != nil {
+		abort(errFailOnError)
+		return false
 	}
+	return true
 }
 
 // For more examples of using go-gh, see:
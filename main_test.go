@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const prURL = "https://api.github.com/repos/cli/cli/pulls/1"
+const issueURL = "https://api.github.com/repos/cli/cli/issues/2"
+
+func TestFromABot(t *testing.T) {
+	tests := []struct {
+		name  string
+		login string
+		kind  string
+		want  bool
+	}{
+		{"app", "dependabot[bot]", "Bot", true},
+		{"bot suffix only", "renovate[bot]", "User", true},
+		{"bot type only", "some-app", "Bot", true},
+		{"person", "octocat", "User", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := new(PullRequest)
+			pr.User.Login, pr.User.Type = tt.login, tt.kind
+			if got := from_a_bot(pr); got != tt.want {
+				t.Errorf("from_a_bot(%s, %s) = %v, want %v", tt.login, tt.kind, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClosedPR(t *testing.T) {
+	for state, want := range map[string]bool{"closed": true, "open": false, "": false} {
+		if got := closedPR(&PullRequest{State: state}); got != want {
+			t.Errorf("closedPR(%q) = %v, want %v", state, got, want)
+		}
+	}
+}
+
+func TestTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		subject  Notification
+		response string
+		check    func(NotificationResult) bool
+	}{
+		{
+			name:     "PR from a bot",
+			subject:  notification("1", "PullRequest", prURL),
+			response: `{"state":"open","user":{"login":"dependabot[bot]","type":"Bot"}}`,
+			check:    func(r NotificationResult) bool { return r.BotPR && !r.ClosedPR && !r.MergedPR },
+		},
+		{
+			name:     "merged PR",
+			subject:  notification("1", "PullRequest", prURL),
+			response: `{"state":"closed","merged":true,"user":{"login":"octocat","type":"User"}}`,
+			check:    func(r NotificationResult) bool { return r.MergedPR && !r.ClosedPR && !r.BotPR },
+		},
+		{
+			name:     "PR closed without merging",
+			subject:  notification("1", "PullRequest", prURL),
+			response: `{"state":"closed","user":{"login":"octocat","type":"User"}}`,
+			check:    func(r NotificationResult) bool { return r.ClosedPR && !r.MergedPR },
+		},
+		{
+			name:     "closed issue",
+			subject:  notification("2", "Issue", issueURL),
+			response: `{"state":"closed"}`,
+			check:    func(r NotificationResult) bool { return r.ClosedIssue },
+		},
+		{
+			name:     "open issue",
+			subject:  notification("2", "Issue", issueURL),
+			response: `{"state":"open"}`,
+			check:    func(r NotificationResult) bool { return !r.ClosedIssue && r.Err == nil },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRun()
+			client := newFakeClient().on(http.MethodGet, tt.subject.Subject.Url, fakeResponse{body: tt.response})
+			result := tag(context.Background(), client, tt.subject)
+			if result.Err != nil {
+				t.Fatalf("tag: %v", result.Err)
+			}
+			if !tt.check(result) {
+				t.Errorf("tag = %+v", result)
+			}
+		})
+	}
+}
+
+func TestTagReportsFetchErrors(t *testing.T) {
+	resetRun()
+	result := tag(context.Background(), newFakeClient(), notification("1", "PullRequest", prURL))
+	if result.Err == nil {
+		t.Fatal("tag of a missing PR succeeded")
+	}
+	if decide(result).Deleted {
+		t.Error("a notification that failed to tag got deleted")
+	}
+}
+
+func TestTagArchivedRepoStillFetchesSubject(t *testing.T) {
+	set(t, &onlyArchivedRepos, true)
+	set(t, &protectLabels, []string{"keep"})
+	set(t, &skipPRsFromBots, true)
+	resetRun()
+	n := notification("1", "PullRequest", prURL)
+	client := newFakeClient().
+		on(http.MethodGet, n.Repository.Url, fakeResponse{body: `{"archived":true}`}).
+		on(http.MethodGet, prURL, fakeResponse{body: `{"state":"open","labels":[{"name":"Keep"}],"user":{"login":"octocat","type":"User"}}`})
+
+	result := tag(context.Background(), client, n)
+	if !result.ArchivedRepo || !result.Labeled {
+		t.Fatalf("tag = %+v, want an archived repo and a protected label", result)
+	}
+	if status := decide(result); status.Deleted || !status.Protected {
+		t.Errorf("decide = %+v, want it protected by its label", status)
+	}
+}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name   string
+		status NotificationResult
+		flags  func(t *testing.T)
+		want   bool
+	}{
+		{"bot", NotificationResult{BotPR: true}, nil, true},
+		{"bot with --skip-bots", NotificationResult{BotPR: true}, func(t *testing.T) { set(t, &skipPRsFromBots, true) }, false},
+		{"merged", NotificationResult{MergedPR: true}, nil, true},
+		{"merged with --skip-merged", NotificationResult{MergedPR: true}, func(t *testing.T) { set(t, &skipMergedPRs, true) }, false},
+		{"closed issue", NotificationResult{ClosedIssue: true}, nil, true},
+		{"nothing applies", NotificationResult{}, nil, false},
+		{"excluded bot", NotificationResult{BotPR: true, Excluded: true}, nil, false},
+		{"recent bot", NotificationResult{BotPR: true, Recent: true}, nil, false},
+		{"labeled bot", NotificationResult{BotPR: true, Labeled: true}, nil, false},
+		{"archived repository", NotificationResult{ArchivedRepo: true}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set(t, &neverDeleteReasons, nil)
+			if tt.flags != nil {
+				tt.flags(t)
+			}
+			tt.status.Notification = notification("1", "PullRequest", prURL)
+			if got := decide(tt.status); got.Deleted != tt.want {
+				t.Errorf("decide(%+v).Deleted = %v, want %v", tt.status, got.Deleted, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecideMarksReadInstead(t *testing.T) {
+	set(t, &markRead, true)
+	got := decide(NotificationResult{Notification: notification("1", "PullRequest", prURL), BotPR: true})
+	if got.Deleted || !got.MarkedRead {
+		t.Errorf("decide with --mark-read = %+v, want marked read only", got)
+	}
+}
+
+func TestInAgeWindow(t *testing.T) {
+	now := time.Date(2024, 5, 31, 10, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		name      string
+		updated   time.Time
+		olderThan time.Duration
+		newerThan time.Duration
+		want      bool
+	}{
+		{"no window", now, 0, 0, true},
+		{"exactly --older-than", now.Add(-30 * day), 30 * day, 0, true},
+		{"just short of --older-than", now.Add(-30*day + time.Second), 30 * day, 0, false},
+		{"beyond --older-than", now.Add(-31 * day), 30 * day, 0, true},
+		{"exactly --newer-than", now.Add(-day), 0, day, true},
+		{"just beyond --newer-than", now.Add(-day - time.Second), 0, day, false},
+		{"within --newer-than", now.Add(-time.Hour), 0, day, true},
+		{"on the lower edge of both", now.Add(-day), day, 7 * day, true},
+		{"on the upper edge of both", now.Add(-7 * day), day, 7 * day, true},
+		{"outside both", now.Add(-8 * day), day, 7 * day, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set(t, &olderThan, durationValue(tt.olderThan))
+			set(t, &newerThan, durationValue(tt.newerThan))
+			n := notification("1", "Issue", issueURL)
+			n.UpdatedAt = tt.updated.Format(time.RFC3339)
+			if got := inAgeWindow(n, now); got != tt.want {
+				t.Errorf("inAgeWindow(%s) = %v, want %v", n.UpdatedAt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInAgeWindowWithoutTimestamp(t *testing.T) {
+	set(t, &olderThan, durationValue(time.Hour))
+	n := notification("1", "Issue", issueURL)
+	n.UpdatedAt = "not a time"
+	if inAgeWindow(n, time.Now()) {
+		t.Error("a notification without a valid update time is in the window")
+	}
+}
+
+func TestDeletesRead(t *testing.T) {
+	tests := []struct {
+		deleteAll   bool
+		skipRead    bool
+		readReasons []string
+		reason      string
+		want        bool
+	}{
+		{true, false, nil, "subscribed", true},
+		{true, true, []string{"mention"}, "subscribed", true},
+		{false, true, nil, "subscribed", false},
+		{false, true, []string{"subscribed"}, "subscribed", false},
+		{false, false, nil, "subscribed", true},
+		{false, false, []string{"subscribed"}, "subscribed", true},
+		{false, false, []string{"mention"}, "subscribed", false},
+	}
+	for _, tt := range tests {
+		set(t, &deleteAllRead, tt.deleteAll)
+		set(t, &skipReadNotifications, tt.skipRead)
+		set(t, &readReasons, tt.readReasons)
+		n := notification("1", "Issue", issueURL)
+		n.Reason = tt.reason
+		if got := deletesRead(n); got != tt.want {
+			t.Errorf("deletesRead with --delete-all-read=%v --skip-read=%v --read-reasons=%v, reason %s = %v, want %v",
+				tt.deleteAll, tt.skipRead, tt.readReasons, tt.reason, got, tt.want)
+		}
+	}
+}
+
+func TestTagWithoutSubjectURL(t *testing.T) {
+	resetRun()
+	set(t, &neverDeleteReasons, nil)
+	client := newFakeClient()
+	discussion := notification("1", "Discussion", "")
+
+	result := tag(context.Background(), client, discussion)
+	if result.Err != nil {
+		t.Fatalf("tag: %v", result.Err)
+	}
+	if len(client.calls) != 0 {
+		t.Errorf("tag made requests %v for a subject without url", client.calls)
+	}
+	if decide(result).Deleted {
+		t.Error("unread notification without subject url got deleted")
+	}
+
+	discussion.Unread = false
+	if !decide(tag(context.Background(), client, discussion)).Deleted {
+		t.Error("read notification without subject url wasn't deleted")
+	}
+}
+
+func TestActTreatsMissingThreadAsDeleted(t *testing.T) {
+	n := notification("42", "PullRequest", prURL)
+	client := newFakeClient().on(http.MethodDelete, threadPath(n), fakeResponse{status: http.StatusNotFound})
+
+	got := act(client, NotificationResult{Notification: n, Deleted: true})
+	if got.Err != nil || !got.Deleted {
+		t.Errorf("act on a thread that's gone = %+v, want deleted without error", got)
+	}
+	if client.called(http.MethodDelete, threadPath(n)) != 1 {
+		t.Errorf("requests = %v, want one DELETE of %s", client.calls, threadPath(n))
+	}
+}
+
+func TestActReportsFailedDeletes(t *testing.T) {
+	n := notification("42", "PullRequest", prURL)
+	client := newFakeClient().on(http.MethodDelete, threadPath(n), fakeResponse{status: http.StatusForbidden})
+
+	got := act(client, NotificationResult{Notification: n, Deleted: true})
+	if got.Err == nil || got.Deleted {
+		t.Errorf("act on a forbidden delete = %+v, want an error and not deleted", got)
+	}
+}
+
+func TestTagCheckSuite(t *testing.T) {
+	resetRun()
+	set(t, &neverDeleteReasons, nil)
+	client := newFakeClient()
+	suite := notification("1", "CheckSuite", "")
+	suite.Reason = "ci_activity"
+	suite.Subject.Title = "CI workflow run failed for main branch"
+
+	set(t, &ciAsBot, true)
+	result := tag(context.Background(), client, suite)
+	if !result.CI || !decide(result).Deleted {
+		t.Errorf("CheckSuite with --ci-as-bot = %+v, want CI and deleted", result)
+	}
+	set(t, &skipPRsFromBots, true)
+	if decide(result).Deleted {
+		t.Error("CheckSuite deleted despite --skip-bots")
+	}
+
+	set(t, &ciAsBot, false)
+	set(t, &skipPRsFromBots, false)
+	if result := tag(context.Background(), client, suite); result.CI || decide(result).Deleted {
+		t.Errorf("unread CheckSuite without --ci-as-bot = %+v, want kept", result)
+	}
+	if len(client.calls) != 0 {
+		t.Errorf("tagging a CheckSuite made requests %v", client.calls)
+	}
+}
+
+func TestReadUnreadTruthTable(t *testing.T) {
+	flags := map[string]*bool{
+		"--skip-bots":   &skipPRsFromBots,
+		"--skip-closed": &skipClosedPRs,
+		"--skip-read":   &skipReadNotifications,
+		"--skip-unread": &skipUnread,
+	}
+	tests := []struct {
+		read    bool
+		subject string
+		flag    string
+		want    bool
+	}{
+		{false, "bot", "", true},
+		{false, "closed", "", true},
+		{true, "bot", "", true},
+		{true, "closed", "", true},
+
+		{false, "bot", "--skip-bots", false},
+		{false, "closed", "--skip-bots", true},
+		{true, "bot", "--skip-bots", true},
+		{true, "closed", "--skip-bots", true},
+
+		{false, "bot", "--skip-closed", true},
+		{false, "closed", "--skip-closed", false},
+		{true, "bot", "--skip-closed", true},
+		{true, "closed", "--skip-closed", true},
+
+		{false, "bot", "--skip-read", true},
+		{false, "closed", "--skip-read", true},
+		{true, "bot", "--skip-read", true},
+		{true, "closed", "--skip-read", true},
+
+		{false, "bot", "--skip-unread", false},
+		{false, "closed", "--skip-unread", false},
+		{true, "bot", "--skip-unread", true},
+		{true, "closed", "--skip-unread", true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("read=%v %s %s", tt.read, tt.subject, tt.flag), func(t *testing.T) {
+			set(t, &neverDeleteReasons, nil)
+			if tt.flag != "" {
+				set(t, flags[tt.flag], true)
+			}
+			n := notification("1", "PullRequest", prURL)
+			n.Unread = !tt.read
+
+			// The same as tag, short of fetching the PR
+			status := NotificationResult{Notification: n}
+			if status.FilteredBy = filteredBy(n); status.FilteredBy == "" {
+				status.Read = read(n)
+				status.BotPR = tt.subject == "bot"
+				status.ClosedPR = tt.subject == "closed"
+			}
+			if got := decide(status).Deleted; got != tt.want {
+				t.Errorf("deleted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasAnyReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		reason  string
+		reasons []string
+		want    bool
+	}{
+		{"exact", "mention", []string{"mention"}, true},
+		{"other case", "Mention", []string{"MENTION"}, true},
+		{"surrounding whitespace", "mention", []string{" mention "}, true},
+		{"one of many", "state_change", []string{"mention", "state_change"}, true},
+		{"not listed", "subscribed", []string{"mention", "team_mention"}, false},
+		{"prefix only", "team_mention", []string{"team"}, false},
+		{"empty list", "mention", nil, false},
+		{"empty reason", "", []string{"mention"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := notification("1", "Issue", issueURL)
+			n.Reason = tt.reason
+			if got := hasAnyReason(n, tt.reasons...); got != tt.want {
+				t.Errorf("hasAnyReason(%q, %q) = %v, want %v", tt.reason, tt.reasons, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReasonFlagsIgnoreCase(t *testing.T) {
+	n := notification("1", "Issue", issueURL)
+	n.Reason = "state_change"
+
+	set(t, &reasons, nil)
+	if !hasReason(n) {
+		t.Error("no --reason doesn't match every reason")
+	}
+	set(t, &reasons, []string{"State_Change"})
+	if !hasReason(n) {
+		t.Error("--reason State_Change doesn't match state_change")
+	}
+	set(t, &onlyStateChanges, true)
+	if !stateChangesMatch(n) {
+		t.Error("--only-state-changes doesn't match state_change")
+	}
+	set(t, &onlyStateChanges, false)
+	set(t, &skipStateChanges, true)
+	if stateChangesMatch(n) {
+		t.Error("--skip-state-changes matches state_change")
+	}
+}
+
+func TestTypeDeletesUnclassifiedTypes(t *testing.T) {
+	set(t, &subjectTypes, []string{"release", "PullRequest"})
+	resetRun()
+	client := newFakeClient().on(http.MethodGet, prURL, fakeResponse{body: `{"state":"open","user":{"login":"octocat","type":"User"}}`})
+
+	release := tag(context.Background(), client, notification("1", "Release", "https://api.github.com/repos/cli/cli/releases/1"))
+	if status := decide(release); !status.Deleted || !status.SelectedType {
+		t.Errorf("unread release = %+v, want it deleted for its type", status)
+	}
+	pr := tag(context.Background(), client, notification("2", "PullRequest", prURL))
+	if status := decide(pr); status.Deleted || status.SelectedType {
+		t.Errorf("open PR = %+v, want it kept", status)
+	}
+	commit := tag(context.Background(), client, notification("3", "Commit", ""))
+	if commit.FilteredBy != "--type" {
+		t.Errorf("commit filtered by %q, want --type", commit.FilteredBy)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// metrics add up the summaries of all runs of a process for --metrics-file,
+// so with --watch the counters keep growing like Prometheus expects
+type metrics struct {
+	seen       int
+	deleted    int
+	markedRead int
+	errors     int
+}
+
+var totalMetrics metrics
+
+// recordMetrics adds a run to the totals and rewrites the --metrics-file in
+// the Prometheus text format, through a rename so no half written file is
+// ever scraped
+func recordMetrics(path string, totals *summary) error {
+	totalMetrics.seen += totals.Seen
+	totalMetrics.deleted += totals.Deleted.Total
+	totalMetrics.markedRead += totals.MarkedRead.Total
+	totalMetrics.errors += totals.Errors
+
+	var text strings.Builder
+	write := func(name, help string, value int64) {
+		fmt.Fprintf(&text, "# HELP gh_nuke_%s %s\n# TYPE gh_nuke_%s counter\ngh_nuke_%s %d\n", name, help, name, name, value)
+	}
+	write("notifications_seen_total", "Notifications seen.", int64(totalMetrics.seen))
+	write("deleted_total", "Notifications deleted.", int64(totalMetrics.deleted))
+	write("marked_read_total", "Notifications marked as read.", int64(totalMetrics.markedRead))
+	write("errors_total", "Notifications and pages that failed.", int64(totalMetrics.errors))
+	write("api_requests_total", "Requests sent to the GitHub API.", limiter.requests.Load())
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.WriteString(text.String()); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	// node_exporter needs to read it, CreateTemp makes it private
+	if err := os.Chmod(temp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
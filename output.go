@@ -0,0 +1,457 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/term"
+)
+
+// plainMarkers maps each emoji marker to the ASCII tag used with --no-emoji
+var plainMarkers = map[string]string{
+	BotPR:        "[bot]",
+	CI:           "[ci]",
+	ClosedPR:     "[closed]",
+	MergedPR:     "[merged]",
+	ClosedIssue:  "[closed-issue]",
+	Read:         "[read]",
+	Deleted:      "[del]",
+	MarkedRead:   "[marked-read]",
+	Unsubscribed: "[unsubscribed]",
+	Excluded:     "[excluded]",
+	Recent:       "[recent]",
+	Protected:    "[protected]",
+	Error:        "[error]",
+	OverLimit:    "[limit]",
+	ArchivedRepo: "[archived]",
+	SelectedType: "[type]",
+}
+
+// ANSI escape sequences used when colorEnabled
+const (
+	red    = "\033[31m"
+	bold   = "\033[1m"
+	dim    = "\033[2m"
+	normal = "\033[22m"
+	reset  = "\033[0m"
+)
+
+// output is where results are printed, the file given by --output-file or
+// stdout, while progress, logs and the summary stay on the terminal
+var output io.Writer = os.Stdout
+
+// openOutput points output at path, creating or truncating it
+func openOutput(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	output = file
+	return nil
+}
+
+// closeOutput closes the --output-file, if any, a failed write counts as an
+// error of the run
+func closeOutput(totals *summary) {
+	file, ok := output.(*os.File)
+	if !ok || file == os.Stdout {
+		return
+	}
+	if err := file.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: writing %s: %v\n", outputPath, err)
+		totals.Errors++
+	}
+}
+
+// colorEnabled is worked out from --color once flags are parsed
+var colorEnabled bool
+
+// displayZone is the time zone of the table, --timezone or local time
+var displayZone = time.Local
+
+// displayTime renders an RFC 3339 timestamp of the API in displayZone to the
+// minute, or leaves it as it is if it doesn't parse
+func displayTime(timestamp string) string {
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return timestamp
+	}
+	return parsed.In(displayZone).Format("2006-01-02 15:04")
+}
+
+// tableOutput reports whether the output is meant for humans rather than
+// machines, only then are the header, colors and footer printed
+func tableOutput() bool {
+	return !quiet && !jsonOutput && outputFormat == "table"
+}
+
+// useColor decides on color for the table: never for JSON, CSV or --no-emoji,
+// with auto only on a terminal that doesn't ask for NO_COLOR, never a file
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return tableOutput() && !noEmoji, nil
+	case "never":
+		return false, nil
+	case "auto":
+		return tableOutput() && !noEmoji && outputPath == "" && term.FromEnv().IsColorEnabled(), nil
+	}
+	return false, fmt.Errorf("invalid --color %q, expected auto, always or never", mode)
+}
+
+func marker(emoji string) string {
+	if noEmoji {
+		return plainMarkers[emoji]
+	}
+	return emoji
+}
+
+type jsonResult struct {
+	Type         string `json:"type"`
+	Id           string `json:"id"`
+	Seq          int64  `json:"seq,omitempty"`
+	Repo         string `json:"repo"`
+	Title        string `json:"title"`
+	Url          string `json:"url"`
+	HtmlUrl      string `json:"html_url"`
+	Reason       string `json:"reason"`
+	UpdatedAt    string `json:"updated_at"`
+	Deleted      bool   `json:"deleted"`
+	MarkedRead   bool   `json:"marked_read"`
+	Unsubscribed bool   `json:"unsubscribed"`
+	Excluded     bool   `json:"excluded"`
+	Recent       bool   `json:"recent"`
+	Protected    bool   `json:"protected"`
+	OverLimit    bool   `json:"over_limit"`
+	ArchivedRepo bool   `json:"archived_repo"`
+	SelectedType bool   `json:"selected_type"`
+	Read         bool   `json:"read"`
+	BotPR        bool   `json:"bot_pr"`
+	CI           bool   `json:"ci"`
+	ClosedPR     bool   `json:"closed_pr"`
+	MergedPR     bool   `json:"merged_pr"`
+	ClosedIssue  bool   `json:"closed_issue"`
+	Error        string `json:"error,omitempty"`
+}
+
+func newJSONResult(result NotificationResult) jsonResult {
+	r := jsonResult{
+		Type:         "notification",
+		Id:           result.Notification.Id,
+		Repo:         result.Notification.Repository.FullName,
+		Title:        result.Notification.Subject.Title,
+		Url:          result.Notification.Subject.Url,
+		HtmlUrl:      result.Notification.Subject.HtmlUrl,
+		Reason:       result.Notification.Reason,
+		UpdatedAt:    result.Notification.UpdatedAt,
+		Deleted:      result.Deleted,
+		MarkedRead:   result.MarkedRead,
+		Unsubscribed: result.Unsubscribed,
+		Excluded:     result.Excluded,
+		Recent:       result.Recent,
+		Protected:    result.Protected,
+		OverLimit:    result.OverLimit,
+		ArchivedRepo: result.ArchivedRepo,
+		SelectedType: result.SelectedType,
+		Read:         result.Read,
+		BotPR:        result.BotPR,
+		CI:           result.CI,
+		ClosedPR:     result.ClosedPR,
+		MergedPR:     result.MergedPR,
+		ClosedIssue:  result.ClosedIssue,
+	}
+	if showSeq {
+		r.Seq = result.Notification.Seq
+	}
+	if result.Err != nil {
+		r.Error = result.Err.Error()
+	}
+	return r
+}
+
+// printResults prints results as they arrive and returns the summary of the
+// run. In JSON mode the summary is part of the output already.
+func printResults(results <-chan NotificationResult) *summary {
+	totals := newSummary()
+	if sortBy != "" {
+		results = sortResults(results)
+	}
+	if summaryOnly || quiet {
+		printSummaryOnly(results, totals)
+	} else if groupBy == "repo" {
+		printGroups(results, totals)
+	} else if jsonOutput {
+		printJSON(results, totals)
+	} else if outputFormat == "csv" {
+		printCSV(results, totals)
+		totals.finish()
+	} else if rowTemplate.Template != nil {
+		printTemplate(results, totals)
+		totals.finish()
+	} else if paging {
+		pageTable(results, totals)
+		totals.finish()
+	} else {
+		printTable(results, totals)
+		totals.finish()
+	}
+	if !jsonOutput {
+		printErrors(totals.failed)
+	}
+	return totals
+}
+
+// printErrors lists the notifications that failed after all others, on
+// stderr with any --quiet. In JSON each result carries its error instead.
+func printErrors(failed []NotificationResult) {
+	if len(failed) == 0 {
+		return
+	}
+	counters.clear()
+	fmt.Fprintf(os.Stderr, "Errors (%d):\n", len(failed))
+	for _, result := range failed {
+		fmt.Fprintf(os.Stderr, "  [%s] %s: %v\n", result.Notification.Repository.FullName, result.Notification.Subject.Title, result.Err)
+	}
+}
+
+// printSummaryOnly counts results without printing them, leaving only the
+// summary: printed as the one JSON object of the output, or by the caller
+func printSummaryOnly(results <-chan NotificationResult, totals *summary) {
+	for result := range results {
+		totals.add(result)
+	}
+	totals.finish()
+	if !jsonOutput {
+		return
+	}
+	line, err := json.Marshal(totals)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Fprintf(output, "%s\n", line)
+}
+
+// sortResults waits for all results and hands them on ordered by --sort,
+// newest first for equal keys like the API does
+func sortResults(results <-chan NotificationResult) <-chan NotificationResult {
+	buffered := bufferStatuses(results)
+	key := func(result NotificationResult) string {
+		switch sortBy {
+		case "repo":
+			return strings.ToLower(result.Notification.Repository.FullName)
+		case "reason":
+			return result.Notification.Reason
+		}
+		return ""
+	}
+	sort.SliceStable(buffered, func(i, j int) bool {
+		if a, b := key(buffered[i]), key(buffered[j]); a != b {
+			return a < b
+		}
+		return buffered[i].Notification.UpdatedAt > buffered[j].Notification.UpdatedAt
+	})
+	return replay(buffered)
+}
+
+// pageTable prints the table through the pager, falling back to stdout when
+// it can't be started
+func pageTable(results <-chan NotificationResult, totals *summary) {
+	p, err := startPager(pagerCommand())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: starting pager: %v\n", err)
+		printTable(results, totals)
+		return
+	}
+	output = p.stdin
+	defer func() { output = os.Stdout }()
+	printTable(results, totals)
+	p.close()
+}
+
+func printTable(results <-chan NotificationResult, totals *summary) {
+	header := "Time            \tReason [Repo] Title\tURL"
+	if showSeq {
+		header = "Seq\t" + header
+	}
+	fmt.Fprintln(output, header)
+
+	for result := range results {
+		totals.add(result)
+		counters.clear()
+		fmt.Fprintln(output, formatRow(result))
+	}
+	if totals.Seen == 0 {
+		fmt.Fprintln(output, "No notifications")
+	}
+}
+
+// printTemplate prints every result through --template, one per line
+func printTemplate(results <-chan NotificationResult, totals *summary) {
+	for result := range results {
+		totals.add(result)
+		counters.clear()
+		var row strings.Builder
+		if err := rowTemplate.Execute(&row, result); err != nil {
+			fmt.Fprintf(os.Stderr, "error: --template: %v\n", err)
+			continue
+		}
+		fmt.Fprintln(output, row.String())
+	}
+}
+
+// markers lists everything that applied to a result, followed by a space
+// unless nothing did
+func markers(result NotificationResult) string {
+	reason := ""
+	if result.Err != nil {
+		reason += marker(Error)
+	}
+	if result.Deleted {
+		reason += marker(Deleted)
+	}
+	if result.MarkedRead {
+		reason += marker(MarkedRead)
+	}
+	if result.Unsubscribed {
+		reason += marker(Unsubscribed)
+	}
+	if result.Excluded {
+		reason += marker(Excluded)
+	}
+	if result.Recent {
+		reason += marker(Recent)
+	}
+	if result.Protected {
+		reason += marker(Protected)
+	}
+	if result.OverLimit {
+		reason += marker(OverLimit)
+	}
+	if result.Read {
+		reason += marker(Read)
+	}
+	if result.ClosedPR {
+		reason += marker(ClosedPR)
+	}
+	if result.MergedPR {
+		reason += marker(MergedPR)
+	}
+	if result.ClosedIssue {
+		reason += marker(ClosedIssue)
+	}
+	if result.BotPR {
+		reason += marker(BotPR)
+	}
+	if result.CI {
+		reason += marker(CI)
+	}
+	if result.ArchivedRepo {
+		reason += marker(ArchivedRepo)
+	}
+	if result.SelectedType {
+		reason += marker(SelectedType)
+	}
+
+	if reason != "" {
+		reason += " "
+	}
+	return reason
+}
+
+// formatRow renders a result as a line of the table, colored when enabled:
+// deleted rows in red, kept rows about PRs from bots dimmed, repos in bold
+func formatRow(result NotificationResult) string {
+	repo := result.Notification.Repository.FullName
+	row := func(repo string) string {
+		line := fmt.Sprintf("%s\t%s[%s] %s", displayTime(result.Notification.UpdatedAt), markers(result), repo, result.Notification.Subject.Title)
+		if showSeq {
+			line = fmt.Sprintf("#%d\t%s", result.Notification.Seq, line)
+		}
+		if url := displayURL(result.Notification); url != "" {
+			line += "\t" + url
+		}
+		return line
+	}
+	if !colorEnabled {
+		return row(repo)
+	}
+	switch {
+	case result.Deleted || result.MarkedRead:
+		return red + row(bold+repo+normal) + reset
+	case result.BotPR || result.CI:
+		return dim + row(repo) + reset
+	}
+	return row(bold + repo + normal)
+}
+
+// printJSON streams results as the elements of a single JSON array, followed
+// by the summary object, so the output stays valid JSON even when there are
+// no results at all: an empty inbox is an array holding just the summary
+func printJSON(results <-chan NotificationResult, totals *summary) {
+	array := new(jsonArray)
+	for result := range results {
+		totals.add(result)
+		array.write(newJSONResult(result))
+	}
+	totals.finish()
+	array.write(totals)
+	array.close()
+}
+
+// jsonArray prints values as the elements of a JSON array, one per line
+type jsonArray struct {
+	elements int
+}
+
+func (a *jsonArray) write(v any) {
+	line, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	separator := ",\n"
+	if a.elements == 0 {
+		separator = "[\n"
+	}
+	fmt.Fprintf(output, "%s%s", separator, line)
+	a.elements++
+}
+
+// close ends the array, which always holds the summary by then
+func (a *jsonArray) close() {
+	fmt.Fprintln(output, "\n]")
+}
+
+var csvHeader = []string{"updated_at", "reason", "repo", "title", "url", "deleted", "bot", "closed", "read"}
+
+func printCSV(results <-chan NotificationResult, totals *summary) {
+	writer := csv.NewWriter(output)
+	writer.Write(csvHeader)
+	for result := range results {
+		totals.add(result)
+		writer.Write([]string{
+			result.Notification.UpdatedAt,
+			result.Notification.Reason,
+			result.Notification.Repository.FullName,
+			result.Notification.Subject.Title,
+			displayURL(result.Notification),
+			strconv.FormatBool(result.Deleted),
+			strconv.FormatBool(result.BotPR || result.CI),
+			strconv.FormatBool(result.ClosedPR || result.MergedPR || result.ClosedIssue),
+			strconv.FormatBool(result.Read),
+		})
+		writer.Flush()
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// printEmpty prints the results of an empty inbox and returns the output
+func printEmpty(t *testing.T) string {
+	t.Helper()
+	var buffer bytes.Buffer
+	set[io.Writer](t, &output, &buffer)
+	results := make(chan NotificationResult)
+	close(results)
+	printResults(results)
+	return buffer.String()
+}
+
+func TestEmptyInboxTable(t *testing.T) {
+	set(t, &outputFormat, "table")
+	got := printEmpty(t)
+	if !strings.Contains(got, "No notifications") {
+		t.Errorf("table of an empty inbox = %q, want it to say No notifications", got)
+	}
+}
+
+func TestEmptyInboxJSON(t *testing.T) {
+	set(t, &jsonOutput, true)
+	got := printEmpty(t)
+	var elements []map[string]any
+	if err := json.Unmarshal([]byte(got), &elements); err != nil {
+		t.Fatalf("JSON of an empty inbox %q: %v", got, err)
+	}
+	if len(elements) != 1 || elements[0]["type"] != "summary" || elements[0]["seen"] != 0.0 {
+		t.Errorf("JSON of an empty inbox = %v, want just a summary of nothing seen", elements)
+	}
+}
+
+func TestEmptyInboxSummaryOnlyJSON(t *testing.T) {
+	set(t, &jsonOutput, true)
+	set(t, &summaryOnly, true)
+	got := printEmpty(t)
+	var totals map[string]any
+	if err := json.Unmarshal([]byte(got), &totals); err != nil || totals["type"] != "summary" {
+		t.Errorf("--summary-only JSON of an empty inbox = %q, %v", got, err)
+	}
+}
+
+func TestEmptyInboxGroupedJSON(t *testing.T) {
+	set(t, &jsonOutput, true)
+	set(t, &groupBy, "repo")
+	got := printEmpty(t)
+	var elements []map[string]any
+	if err := json.Unmarshal([]byte(got), &elements); err != nil || len(elements) != 1 {
+		t.Errorf("--group-by repo JSON of an empty inbox = %q, %v", got, err)
+	}
+}
+
+func TestEmptyInboxCSV(t *testing.T) {
+	set(t, &outputFormat, "csv")
+	got := printEmpty(t)
+	if want := strings.Join(csvHeader, ",") + "\n"; got != want {
+		t.Errorf("CSV of an empty inbox = %q, want just the header %q", got, want)
+	}
+}
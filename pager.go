@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/config"
+	"github.com/cli/go-gh/v2/pkg/term"
+)
+
+// paging is decided once flags are parsed: only the table is paged, and only
+// when it goes to a terminal
+var paging bool
+
+func usePager() bool {
+	return !noPager && !watch && tableOutput() && outputPath == "" && term.IsTerminal(os.Stdout) && pagerCommand() != ""
+}
+
+// pagerCommand finds the pager the way gh does: GH_PAGER, the pager of the gh
+// config, then PAGER. Empty or cat means none.
+func pagerCommand() string {
+	command, ok := os.LookupEnv("GH_PAGER")
+	if !ok {
+		if cfg, err := config.Read(nil); err == nil {
+			command, _ = cfg.Get([]string{"pager"})
+		}
+	}
+	if command == "" {
+		command = os.Getenv("PAGER")
+	}
+	if command == "cat" {
+		return ""
+	}
+	return command
+}
+
+// pager is a running pager reading what's written to it
+type pager struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+func startPager(command string) (*pager, error) {
+	args := strings.Fields(command)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	if _, ok := os.LookupEnv("LV"); !ok {
+		cmd.Env = append(cmd.Env, "LV=-c")
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &pager{cmd: cmd, stdin: stdin}, nil
+}
+
+// close ends the input and waits for the pager to quit, so it has restored
+// the terminal before anything else is printed
+func (p *pager) close() {
+	p.stdin.Close()
+	if err := p.cmd.Wait(); err != nil {
+		if _, quit := err.(*exec.ExitError); !quit {
+			fmt.Fprintf(os.Stderr, "error: pager: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Actions a plan can list for a notification
+const (
+	actionUnsubscribe = "unsubscribe"
+	actionMarkRead    = "mark_read"
+	actionDelete      = "delete"
+)
+
+// plan is written by --plan-out during a dry run and carried out by
+// --apply-plan, possibly after editing it
+type plan struct {
+	CreatedAt time.Time   `json:"created_at"`
+	Entries   []planEntry `json:"entries"`
+}
+
+type planEntry struct {
+	Id      string   `json:"id"`
+	Repo    string   `json:"repo"`
+	Title   string   `json:"title"`
+	Actions []string `json:"actions"`
+}
+
+// planned collects the entries of --plan-out as deleters decide on them, it's
+// nil unless --plan-out was given
+var planned *planRecorder
+
+type planRecorder struct {
+	mu      sync.Mutex
+	entries []planEntry
+}
+
+func (p *planRecorder) record(status NotificationResult) {
+	if p == nil {
+		return
+	}
+	var actions []string
+	if status.Unsubscribed {
+		actions = append(actions, actionUnsubscribe)
+	}
+	if status.MarkedRead {
+		actions = append(actions, actionMarkRead)
+	}
+	if status.Deleted {
+		actions = append(actions, actionDelete)
+	}
+	if len(actions) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries = append(p.entries, planEntry{
+		Id:      status.Notification.Id,
+		Repo:    status.Notification.Repository.FullName,
+		Title:   status.Notification.Subject.Title,
+		Actions: actions,
+	})
+}
+
+func (p *planRecorder) write(path string) error {
+	data, err := json.MarshalIndent(plan{CreatedAt: time.Now().UTC(), Entries: p.entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// applyPlan carries out a plan without fetching and tagging notifications
+// again. Threads that no longer exist are reported and left out.
+func applyPlan(client restClient, path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: reading plan: %v\n", err)
+		return exitFailed
+	}
+	var loaded plan
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
+		return exitFailed
+	}
+
+	results := make(chan NotificationResult)
+	go func() {
+		defer close(results)
+		for _, entry := range loaded.Entries {
+			results <- applyEntry(client, entry)
+		}
+	}()
+	totals := printResults(results)
+	closeOutput(totals)
+	audit.close()
+	if tableOutput() {
+		fmt.Println("Done 🎉")
+		totals.print()
+	}
+	return totals.exitCode()
+}
+
+func applyEntry(client restClient, entry planEntry) NotificationResult {
+	status := NotificationResult{}
+	status.Notification.Id = entry.Id
+	status.Notification.ThreadID = parseThreadID(status.Notification)
+	status.Notification.Repository.FullName = entry.Repo
+	status.Notification.Subject.Title = entry.Title
+
+	for _, action := range entry.Actions {
+		switch action {
+		case actionUnsubscribe:
+			status.Unsubscribed = true
+		case actionMarkRead:
+			status.MarkedRead = true
+		case actionDelete:
+			status.Deleted = true
+		default:
+			status.Err = fmt.Errorf("unknown action %q in plan", action)
+			return status
+		}
+	}
+
+	err := withRetry(func() error { return client.Get(threadPath(status.Notification), nil) })
+	if notFound(err) {
+		status.Err = errors.New("thread no longer exists")
+		return status
+	}
+	if err != nil {
+		status.Err = fmt.Errorf("checking thread: %w", err)
+		return status
+	}
+
+	status = act(client, status)
+	if status.Deleted && !dryRun {
+		audit.record(status.Notification)
+	}
+	return status
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// workerPool scales the number of active tagging workers, the stage making
+// the most API requests, between --min-workers and --max-workers. It adds a
+// worker while plenty of the rate limit is left and parks one when it runs
+// low. Workers beyond the active count wait before taking more work.
+type workerPool struct {
+	active   atomic.Int64
+	released atomic.Bool
+	min, max int64
+}
+
+const (
+	scaleUpHeadroom   = 0.5
+	scaleDownHeadroom = 0.1
+	scaleInterval     = time.Second
+)
+
+func newWorkerPool(min, max int) *workerPool {
+	if max <= 0 || max > fetchWorkers {
+		max = fetchWorkers
+	}
+	if min <= 0 || min > max {
+		min = max
+	}
+	pool := &workerPool{min: int64(min), max: int64(max)}
+	pool.active.Store(pool.min)
+	return pool
+}
+
+// size is the number of workers to start, all of which may become active
+func (p *workerPool) size() int {
+	return int(p.max)
+}
+
+// wait blocks worker index while it's parked, and reports false once ctx is
+// cancelled
+func (p *workerPool) wait(ctx context.Context, index int) bool {
+	for !p.released.Load() && int64(index) >= p.active.Load() {
+		select {
+		case <-time.After(scaleInterval / 4):
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// release activates all workers, so parked ones notice there is no more
+// work once all notifications have been streamed
+func (p *workerPool) release() {
+	p.released.Store(true)
+}
+
+// scale adjusts the active count until ctx is cancelled, done is closed or
+// the pool is released
+func (p *workerPool) scale(ctx context.Context, done <-chan struct{}) {
+	if p.min == p.max {
+		return
+	}
+	ticker := time.NewTicker(scaleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		}
+		headroom, known := limiter.headroom()
+		if p.released.Load() {
+			return
+		}
+		if !known {
+			continue
+		}
+		active := p.active.Load()
+		switch {
+		case headroom > scaleUpHeadroom && active < p.max:
+			active++
+		case headroom < scaleDownHeadroom && active > p.min:
+			active--
+		default:
+			continue
+		}
+		p.active.Store(active)
+		slog.Info("scaled tagging workers", "active", active, "headroom", headroom)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/term"
+)
+
+// progress counts notifications as they pass through the pipeline stages and
+// keeps a live counter on stderr while results are printed to stdout
+type progress struct {
+	fetched atomic.Int64
+	tagged  atomic.Int64
+	deleted atomic.Int64
+
+	// total is the estimated number of notifications, 0 when unknown
+	total   atomic.Int64
+	started time.Time
+
+	// enabled is read by whoever prints, from any goroutine
+	enabled atomic.Bool
+	mu      sync.Mutex
+}
+
+var counters progress
+
+// start begins rendering the counter unless stdout is not a terminal, JSON is
+// printed there, it's paged or --quiet is set, and returns a function
+// stopping it again
+func (p *progress) start() (stop func()) {
+	p.enabled.Store((!jsonOutput || outputPath != "") && !quiet && !paging && term.IsTerminal(os.Stdout))
+	if !p.enabled.Load() {
+		return func() {}
+	}
+	p.started = time.Now()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.render()
+			case <-done:
+				p.clear()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (p *progress) render() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(os.Stderr, "\r\033[Kfetched %d, tagged %d, deleted %d%s", p.fetched.Load(), p.tagged.Load(), p.deleted.Load(), p.eta())
+}
+
+// eta guesses the time left from the throughput of tagging so far, it's
+// empty as long as there's nothing to guess from
+func (p *progress) eta() string {
+	total, tagged := p.total.Load(), p.tagged.Load()
+	if total == 0 || tagged == 0 || tagged >= total {
+		return ""
+	}
+	elapsed := time.Since(p.started)
+	left := time.Duration(float64(elapsed) / float64(tagged) * float64(total-tagged))
+	return fmt.Sprintf(" of ~%d, ~%s left", total, left.Round(time.Second))
+}
+
+// clear wipes the counter so a result line can be printed in its place, the
+// next tick renders it again below
+func (p *progress) clear() {
+	if !p.enabled.Load() {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprint(os.Stderr, "\r\033[K")
+}
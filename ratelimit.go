@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimiter is shared by the clients of all workers. It holds back requests
+// once GitHub reports the rate limit as exhausted until the limit resets, and
+// optionally spaces requests out to stay below --max-rate per minute.
+type rateLimiter struct {
+	next     http.RoundTripper
+	interval time.Duration
+
+	mu        sync.Mutex
+	resumeAt  time.Time
+	nextSlot  time.Time
+	remaining int
+	limit     int
+
+	// requests counts the requests sent, for --metrics-file
+	requests atomic.Int64
+}
+
+var limiter = &rateLimiter{next: http.DefaultTransport}
+
+func (l *rateLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := l.wait(req); err != nil {
+		return nil, err
+	}
+	l.requests.Add(1)
+	response, err := l.next.RoundTrip(req)
+	if err == nil {
+		l.observe(response.Header)
+	}
+	return response, err
+}
+
+func (l *rateLimiter) wait(req *http.Request) error {
+	l.mu.Lock()
+	now := time.Now()
+	until := l.resumeAt
+	if l.interval > 0 {
+		slot := l.nextSlot
+		if slot.Before(now) {
+			slot = now
+		}
+		l.nextSlot = slot.Add(l.interval)
+		if slot.After(until) {
+			until = slot
+		}
+	}
+	l.mu.Unlock()
+
+	delay := time.Until(until)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+func (l *rateLimiter) observe(header http.Header) {
+	remaining, errRemaining := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	limit, errLimit := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if errRemaining == nil && errLimit == nil {
+		l.mu.Lock()
+		l.remaining, l.limit = remaining, limit
+		l.mu.Unlock()
+	}
+
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	resumeAt := time.Unix(reset, 0)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if resumeAt.After(l.resumeAt) {
+		l.resumeAt = resumeAt
+		fmt.Fprintf(os.Stderr, "rate limit exhausted, waiting until %s\n", resumeAt.Format(time.TimeOnly))
+	}
+}
+
+// headroom is the share of the rate limit still remaining as last reported
+// by GitHub, if it reported one yet
+func (l *rateLimiter) headroom() (float64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit <= 0 {
+		return 0, false
+	}
+	return float64(l.remaining) / float64(l.limit), true
+}
+
+// rateLimited reports whether a response header signals an exhausted limit
+func rateLimited(statusCode int, header http.Header) bool {
+	return statusCode == http.StatusTooManyRequests ||
+		(statusCode == http.StatusForbidden && header.Get("X-RateLimit-Remaining") == "0")
+}
+
+// repoThrottle spaces out the actions on notifications of the same repository
+// to --max-repo-rate per minute, since GitHub's secondary rate limits punish
+// bursts of activity on a single repository. Different repositories don't
+// hold each other up.
+type repoThrottle struct {
+	interval time.Duration
+
+	mu       sync.Mutex
+	nextSlot map[string]time.Time
+}
+
+var repoLimiter = &repoThrottle{nextSlot: map[string]time.Time{}}
+
+func (t *repoThrottle) wait(repo string) {
+	if t.interval == 0 {
+		return
+	}
+	t.mu.Lock()
+	slot := t.nextSlot[repo]
+	if now := time.Now(); slot.Before(now) {
+		slot = now
+	}
+	t.nextSlot[repo] = slot.Add(t.interval)
+	t.mu.Unlock()
+	time.Sleep(time.Until(slot))
+}
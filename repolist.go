@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var repoNameRE = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+
+// readRepoList reads owner/name repositories, one per line, for
+// --skip-repos-file. Blank lines and everything after a # are ignored.
+func readRepoList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		name, _, _ := strings.Cut(scanner.Text(), "#")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !repoNameRE.MatchString(name) {
+			return nil, fmt.Errorf("%s:%d: %q is not an owner/name repository", path, line, name)
+		}
+		names = append(names, name)
+	}
+	return names, scanner.Err()
+}
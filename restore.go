@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// restoreFrom re-subscribes to the threads listed in an --audit-log so that
+// new activity on them shows up in the inbox again. The REST API has no way
+// to mark a thread as unread, so this is as close to undoing a delete as it
+// gets. Threads that no longer exist are skipped and reported.
+func restoreFrom(client restClient, path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: opening audit log: %v\n", err)
+		return exitFailed
+	}
+	defer file.Close()
+
+	restored, missing, failed := 0, 0, 0
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s:%d: %v\n", path, line, err)
+			failed++
+			continue
+		}
+
+		thread := threadPath(Notification{ThreadID: entry.Id})
+		err := withRetry(func() error { return client.Get(thread, nil) })
+		if notFound(err) {
+			fmt.Printf("missing\t[%s] %s\n", entry.Repo, entry.Title)
+			missing++
+			continue
+		}
+		if err == nil && !dryRun {
+			err = withRetry(func() error {
+				return send(client, http.MethodPut, thread+"/subscription", strings.NewReader(`{"ignored":false}`))
+			})
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: [%s] %s: %v\n", entry.Repo, entry.Title, err)
+			failed++
+			continue
+		}
+		fmt.Printf("restored\t[%s] %s\n", entry.Repo, entry.Title)
+		restored++
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: reading audit log: %v\n", err)
+		failed++
+	}
+
+	fmt.Printf("Restored: %d, missing: %d, errors: %d\n", restored, missing, failed)
+	if failed > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
@@ -0,0 +1,33 @@
+#!/usr/bin/env bash
+# Builds the release binaries for gh-extension-precompile with the tag,
+# commit and date stamped in, see version.go. Called with the release tag.
+set -euo pipefail
+
+tag="${1:?usage: script/build.sh <tag>}"
+ldflags="-s -w -X main.version=${tag} -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%FT%TZ)"
+
+platforms=(
+  darwin-amd64
+  darwin-arm64
+  freebsd-386
+  freebsd-amd64
+  freebsd-arm64
+  linux-386
+  linux-amd64
+  linux-arm
+  linux-arm64
+  windows-386
+  windows-amd64
+  windows-arm64
+)
+
+mkdir -p dist
+for p in "${platforms[@]}"; do
+  goos="${p%-*}"
+  goarch="${p#*-}"
+  ext=""
+  if [ "$goos" = "windows" ]; then
+    ext=".exe"
+  fi
+  GOOS="$goos" GOARCH="$goarch" CGO_ENABLED=0 go build -trimpath -ldflags="$ldflags" -o "dist/${p}${ext}"
+done
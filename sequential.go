@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"net/url"
+)
+
+// sequential reports whether --workers 0 asks for the whole pipeline to run
+// without any workers
+func sequential() bool {
+	return numWorkers == 0
+}
+
+// runSequentially takes one notification at a time all the way from
+// fetching over tagging to deleting, in a single goroutine. It's slow, but
+// logs follow one notification after the other and results come in the
+// order they were fetched, which makes for easier debugging. There's no
+// progress line either, as it would only get in the way.
+//
+// Like runConcurrently, it only returns once the notifications are
+// confirmed or picked, so the prompt doesn't compete with the pager.
+func runSequentially(ctx context.Context, abort context.CancelCauseFunc, client restClient, query url.Values, results chan<- NotificationResult) {
+	if !interactiveEnabled() && !needsConfirmation() {
+		go func() {
+			defer close(results)
+			eachTagged(ctx, client, query, func(status NotificationResult) bool {
+				return handle(abort, client, status, results)
+			})
+		}()
+		return
+	}
+
+	var buffered []NotificationResult
+	eachTagged(ctx, client, query, func(status NotificationResult) bool {
+		buffered = append(buffered, status)
+		return true
+	})
+	approve(ctx, buffered)
+	go func() {
+		defer close(results)
+		for _, status := range buffered {
+			if ctx.Err() != nil || !handle(abort, client, status, results) {
+				return
+			}
+		}
+	}()
+}
+
+// eachTagged fetches and tags one notification after the other, calling each
+// with its status until it returns false
+func eachTagged(ctx context.Context, client restClient, query url.Values, each func(NotificationResult) bool) {
+	tagged := func(notification Notification) bool {
+		counters.fetched.Add(1)
+		status := tag(ctx, client, notification)
+		if ctx.Err() != nil {
+			return false
+		}
+		counters.tagged.Add(1)
+		return each(status)
+	}
+	if fromFile != "" {
+		eachInFile(fromFile, tagged)
+	} else {
+		eachNotification(ctx, client, notificationsPath()+"?"+query.Encode(), tagged)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestRunSequentially(t *testing.T) {
+	set(t, &numWorkers, 0)
+	set(t, &dryRun, true)
+	resetRun()
+	client := newFakeClient().
+		on(http.MethodGet, "notifications?", fakeResponse{body: page(t, notification("1", "Issue", issueURL), notification("2", "Issue", issueURL))}).
+		on(http.MethodGet, issueURL, fakeResponse{body: `{"state":"closed"}`})
+
+	ctx, abort := context.WithCancelCause(context.Background())
+	defer abort(nil)
+	results := make(chan NotificationResult)
+	runSequentially(ctx, abort, client, nil, results)
+	var got []string
+	for result := range results {
+		if !result.Deleted {
+			t.Errorf("closed issue %s wasn't deleted", result.Notification.Id)
+		}
+		got = append(got, result.Notification.Id)
+	}
+	if len(got) != 2 || got[0] != "1" || got[1] != "2" {
+		t.Errorf("results came in as %v, want [1 2]", got)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// state is persisted while deleting so an interrupted run can pick up where
+// it left off with --resume. Notifications come newest first, so remembering
+// the update time of the oldest one all newer ones have been handled up to
+// is enough, and unlike a page number it doesn't shift as notifications get
+// deleted.
+type state struct {
+	Before string `json:"before"`
+}
+
+// streamCompleted is set once all notifications have been streamed, only
+// then is the saved state cleared at the end of a run
+var streamCompleted atomic.Bool
+
+// checkpoint follows which notifications have been handled to tell what a
+// run can resume from. Workers handle them in any order, so it's the last of
+// those handled without a gap since the first. Only pages fetched one after
+// the other come newest first, and dry runs have nothing to resume.
+type checkpoint struct {
+	mu      sync.Mutex
+	ordered bool
+	pending map[int64]Notification
+	done    int64
+	last    Notification
+	saved   int64
+}
+
+var checkpoints = newCheckpoint()
+
+func newCheckpoint() *checkpoint {
+	return &checkpoint{pending: map[int64]Notification{}}
+}
+
+// begin is called once notifications are streamed in order
+func (c *checkpoint) begin() {
+	c.mu.Lock()
+	c.ordered = true
+	c.mu.Unlock()
+}
+
+// handled records a notification as dealt with, saving the state every
+// page's worth of them
+func (c *checkpoint) handled(notification Notification) {
+	if dryRun || notification.Seq == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.ordered {
+		return
+	}
+	c.pending[notification.Seq] = notification
+	for {
+		next, ok := c.pending[c.done+1]
+		if !ok {
+			break
+		}
+		delete(c.pending, next.Seq)
+		c.done++
+		c.last = next
+	}
+	if c.done-c.saved >= int64(perPage) {
+		c.saveLocked()
+	}
+}
+
+// save persists how far the run got, if anywhere
+func (c *checkpoint) save() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saveLocked()
+}
+
+func (c *checkpoint) saveLocked() {
+	if dryRun || !c.ordered || c.done == 0 || c.done == c.saved {
+		return
+	}
+	saveState(resumeBefore(c.last))
+	c.saved = c.done
+}
+
+func statePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gh-nuke", "state.json"), nil
+}
+
+func loadState() (state, error) {
+	var saved state
+	path, err := statePath()
+	if err != nil {
+		return saved, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return saved, nil
+	}
+	if err != nil {
+		return saved, err
+	}
+	err = json.Unmarshal(data, &saved)
+	return saved, err
+}
+
+func saveState(current state) {
+	path, err := statePath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	if err == nil {
+		var data []byte
+		data, err = json.Marshal(current)
+		if err == nil {
+			err = os.WriteFile(path, data, 0o644)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cannot save state: %v\n", err)
+	}
+}
+
+func clearState() {
+	path, err := statePath()
+	if err == nil {
+		err = os.Remove(path)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "warning: cannot clear state: %v\n", err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestCheckpointWaitsForGaps(t *testing.T) {
+	set(t, &perPage, 100)
+	c := newCheckpoint()
+	c.begin()
+	for _, seq := range []int64{2, 3, 1, 5} {
+		c.handled(Notification{Id: "n", Seq: seq})
+	}
+	if c.done != 3 || c.last.Seq != 3 {
+		t.Errorf("done = %d, last = %d, want 3 and 3", c.done, c.last.Seq)
+	}
+	c.handled(Notification{Seq: 4})
+	if c.done != 5 {
+		t.Errorf("done = %d, want 5", c.done)
+	}
+}
+
+func TestCheckpointIgnoresUnorderedAndDryRuns(t *testing.T) {
+	set(t, &perPage, 100)
+	c := newCheckpoint()
+	c.handled(Notification{Seq: 1})
+	if c.done != 0 {
+		t.Errorf("unordered: done = %d, want 0", c.done)
+	}
+
+	set(t, &dryRun, true)
+	c.begin()
+	c.handled(Notification{Seq: 1})
+	if c.done != 0 {
+		t.Errorf("dry run: done = %d, want 0", c.done)
+	}
+}
+
+func TestResumeBefore(t *testing.T) {
+	got := resumeBefore(Notification{UpdatedAt: "2024-05-01T10:00:00Z"})
+	if got.Before != "2024-05-01T10:00:01Z" {
+		t.Errorf("resumeBefore = %q, want 2024-05-01T10:00:01Z", got.Before)
+	}
+	if got := resumeBefore(Notification{UpdatedAt: "garbage"}); got.Before != "" {
+		t.Errorf("resumeBefore of a bad time = %q, want empty", got.Before)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// ageBuckets are the columns of the --stats age breakdown, each holding the
+// notifications updated less than upTo ago that don't fit an earlier one
+var ageBuckets = []struct {
+	name string
+	upTo time.Duration
+}{
+	{"today", 24 * time.Hour},
+	{"this week", 7 * 24 * time.Hour},
+	{"this month", 30 * 24 * time.Hour},
+	{"older", 0},
+}
+
+// stats break down all notifications seen for --stats, whatever happened to
+// them
+type stats struct {
+	Ages    map[string]int `json:"ages"`
+	Reasons map[string]int `json:"reasons"`
+	Types   map[string]int `json:"types"`
+}
+
+func newStats() *stats {
+	return &stats{Ages: map[string]int{}, Reasons: map[string]int{}, Types: map[string]int{}}
+}
+
+func (s *stats) add(notification Notification, now time.Time) {
+	s.Reasons[notification.Reason]++
+	s.Types[notification.Subject.Type]++
+	updated, ok := updatedAt(notification)
+	if !ok {
+		return
+	}
+	for _, bucket := range ageBuckets {
+		if bucket.upTo == 0 || now.Sub(updated) < bucket.upTo {
+			s.Ages[bucket.name]++
+			return
+		}
+	}
+}
+
+func (s *stats) print() {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "Age\t")
+	for _, bucket := range ageBuckets {
+		fmt.Fprintf(writer, "  %s\t%d\n", bucket.name, s.Ages[bucket.name])
+	}
+	printCounts(writer, "Reason", s.Reasons)
+	printCounts(writer, "Type", s.Types)
+	writer.Flush()
+}
+
+// printCounts lists counts by key, the most frequent first
+func printCounts(writer *tabwriter.Writer, title string, counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Fprintf(writer, "%s\t\n", title)
+	for _, key := range keys {
+		fmt.Fprintf(writer, "  %s\t%d\n", key, counts[key])
+	}
+}
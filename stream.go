@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func streamNotifications(ctx context.Context, client restClient, requestPath string, notificationsChan chan<- Notification) {
+	defer close(notificationsChan)
+
+	eachNotification(ctx, client, requestPath, func(notification Notification) bool {
+		select {
+		case notificationsChan <- notification:
+			counters.fetched.Add(1)
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// eachNotification calls each for every notification fetched from
+// requestPath on, until it returns false. Without workers, for --workers 0,
+// pages are fetched one after the other and each is called in order.
+func eachNotification(ctx context.Context, client restClient, requestPath string, each func(Notification) bool) {
+	// Notifications updated while paging move up and may show up on the
+	// next page a second time, a second delete of them would only fail
+	seen := map[string]bool{}
+	seenMu := new(sync.Mutex)
+	var seq int64
+	emit := func(notification Notification) bool {
+		seenMu.Lock()
+		duplicate := seen[notification.Id]
+		seen[notification.Id] = true
+		if !duplicate {
+			seq++
+			notification.Seq = seq
+		}
+		seenMu.Unlock()
+		if duplicate {
+			slog.Debug("dropped duplicate notification", "id", notification.Id, "repo", notification.Repository.FullName)
+			return true
+		}
+		return each(notification)
+	}
+
+	page := 1
+	notifications, response, err := fetchPage(ctx, client, requestPath)
+	if err != nil {
+		reportPageError(ctx, page, err)
+		return
+	}
+
+	// Halting after a streak of read notifications needs the pages in order,
+	// without it the remaining pages can be fetched all at once
+	streak := newReadStreak()
+	if streak.limit == 0 && !sequential() {
+		if lastPage, hasLastPage := findLink(response, "last"); hasLastPage {
+			for _, notification := range notifications {
+				if !emit(notification) {
+					return
+				}
+			}
+			if streamPages(ctx, client, lastPage, emit) {
+				streamCompleted.Store(true)
+			}
+			return
+		}
+	}
+
+	checkpoints.begin()
+	for {
+		for _, notification := range notifications {
+			if !emit(notification) {
+				return
+			}
+			if streak.halts(notification) {
+				streamCompleted.Store(true)
+				return
+			}
+		}
+
+		var hasNextPage bool
+		if requestPath, hasNextPage = findNextPage(response); !hasNextPage {
+			break
+		}
+		if maxPages > 0 && page >= maxPages {
+			truncated.Store(true)
+			return
+		}
+		page++
+		notifications, response, err = fetchPage(ctx, client, requestPath)
+		if err != nil {
+			reportPageError(ctx, page, err)
+			return
+		}
+	}
+	streamCompleted.Store(true)
+}
+
+// readStreak decides when to stop streaming: after --halt-after read
+// notifications in a row, the one completing the streak included. The API
+// returns notifications newest first across all pages, so the streak carries
+// over from one page to the next; a run of read notifications is a sign that
+// older ones have been dealt with before, wherever the page boundary falls.
+// An unread notification starts the count over.
+type readStreak struct {
+	limit int
+	count int
+}
+
+// newReadStreak follows --halt-after and --all-pages, a limit of 0 never
+// halts
+func newReadStreak() *readStreak {
+	if allPages || haltAfter <= 0 {
+		return &readStreak{}
+	}
+	return &readStreak{limit: haltAfter}
+}
+
+func (s *readStreak) halts(notification Notification) bool {
+	if notification.Unread {
+		s.count = 0
+		return false
+	}
+	s.count++
+	return s.limit > 0 && s.count >= s.limit
+}
+
+// streamPages fetches pages 2 up to lastPage concurrently and reports
+// whether all of them made it. Downstream processing is unordered anyway.
+// Pages don't arrive in order, so resuming from saved state isn't supported.
+func streamPages(ctx context.Context, client restClient, lastPage string, emit func(Notification) bool) bool {
+	last, err := url.Parse(lastPage)
+	if err != nil {
+		reportPageError(ctx, 0, err)
+		return false
+	}
+	pages, err := strconv.Atoi(last.Query().Get("page"))
+	if err != nil {
+		reportPageError(ctx, 0, fmt.Errorf("no page number in %s", lastPage))
+		return false
+	}
+	if maxPages > 0 && pages > maxPages {
+		pages = maxPages
+		truncated.Store(true)
+	}
+
+	pageNumbers := make(chan int)
+	failed := new(atomic.Bool)
+	wg := new(sync.WaitGroup)
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			defer wg.Done()
+			for page := range pageNumbers {
+				pageURL := *last
+				query := pageURL.Query()
+				query.Set("page", strconv.Itoa(page))
+				pageURL.RawQuery = query.Encode()
+
+				notifications, _, err := fetchPage(ctx, client, pageURL.String())
+				if err != nil {
+					reportPageError(ctx, page, err)
+					failed.Store(true)
+					continue
+				}
+				for _, notification := range notifications {
+					if !emit(notification) {
+						return
+					}
+				}
+			}
+		}()
+	}
+
+feed:
+	for page := 2; page <= pages; page++ {
+		select {
+		case pageNumbers <- page:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(pageNumbers)
+	wg.Wait()
+	return !failed.Load() && ctx.Err() == nil
+}
+
+// fetchPage gets and decodes a page of notifications. The body of the
+// returned response is closed already, it's only good for its headers.
+func fetchPage(ctx context.Context, client restClient, requestPath string) ([]Notification, *http.Response, error) {
+	var response *http.Response
+	err := withRetry(func() (err error) {
+		response, err = client.RequestWithContext(ctx, http.MethodGet, requestPath, nil)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	var raws []json.RawMessage
+	decoder := json.NewDecoder(response.Body)
+	if err := decoder.Decode(&raws); err != nil {
+		return nil, nil, fmt.Errorf("decoding: %w", err)
+	}
+	notifications := make([]Notification, 0, len(raws))
+	for _, raw := range raws {
+		dump.record(raw)
+		var notification Notification
+		if err := json.Unmarshal(raw, &notification); err != nil {
+			reportMalformed(requestPath, err)
+			continue
+		}
+		notification.ThreadID = parseThreadID(notification)
+		notifications = append(notifications, notification)
+	}
+	slog.Info("fetched notifications", "path", requestPath, "count", len(notifications))
+	return notifications, response, nil
+}
+
+// countNotifications estimates how many notifications a run over query goes
+// through, at most what --max-pages lets it fetch
+func countNotifications(ctx context.Context, client restClient, query url.Values) (int, bool) {
+	// with --halt-after the stream may stop on any page, so a count of the
+	// whole inbox would overstate the work
+	if newReadStreak().limit > 0 {
+		return 0, false
+	}
+	count, ok := countAll(ctx, client, query)
+	if ok && maxPages > 0 && count > maxPages*perPage {
+		count = maxPages * perPage
+	}
+	return count, ok
+}
+
+// countAll counts every notification matching query from the last page
+// linked by a listing of one notification per page
+func countAll(ctx context.Context, client restClient, query url.Values) (int, bool) {
+	counting := url.Values{}
+	for key, values := range query {
+		counting[key] = values
+	}
+	counting.Set("per_page", "1")
+	response, err := client.RequestWithContext(ctx, http.MethodGet, notificationsPath()+"?"+counting.Encode(), nil)
+	if err != nil {
+		return 0, false
+	}
+	defer response.Body.Close()
+	lastPage, hasLastPage := findLink(response, "last")
+	if !hasLastPage {
+		var notifications []json.RawMessage
+		if err := json.NewDecoder(response.Body).Decode(&notifications); err != nil {
+			return 0, false
+		}
+		return len(notifications), true
+	}
+	last, err := url.Parse(lastPage)
+	if err != nil {
+		return 0, false
+	}
+	count, err := strconv.Atoi(last.Query().Get("page"))
+	return count, err == nil
+}
+
+// truncated is set when --max-pages kept pages from being fetched
+var truncated atomic.Bool
+
+// pageErrors and rateLimitedPages count pages that couldn't be fetched, for
+// the summary and the exit code
+var pageErrors atomic.Int64
+var rateLimitedPages atomic.Int64
+
+// malformed counts notifications skipped because they couldn't be decoded,
+// while the rest of their page is dealt with as usual
+var malformed atomic.Int64
+
+func reportMalformed(source string, err error) {
+	malformed.Add(1)
+	fmt.Fprintf(os.Stderr, "error: skipping malformed notification in %s: %v\n", source, err)
+}
+
+func reportPageError(ctx context.Context, page int, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	pageErrors.Add(1)
+	if isRateLimited(err) {
+		rateLimitedPages.Add(1)
+	}
+	fmt.Fprintf(os.Stderr, "error: fetching page %d of notifications: %v\n", page, err)
+}
+
+// notificationsQuery asks the API to leave out what --participating,
+// --since and --before would filter anyway, or what an interrupted run has
+// dealt with already
+func notificationsQuery(saved state) url.Values {
+	// all=true adds read notifications to the unread ones. Done ones, i.e.
+	// deleted threads, aren't listed by the REST API at all, with or without
+	// it, so there's no way to include them here; a thread only comes back
+	// from Done once there's new activity on it.
+	query := url.Values{"all": {"true"}, "per_page": {strconv.Itoa(perPage)}}
+	if participating {
+		query.Set("participating", "true")
+	}
+	if !since.IsZero() {
+		query.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	upTo := before.Time
+	if resumed, err := time.Parse(time.RFC3339, saved.Before); err == nil && (upTo.IsZero() || resumed.Before(upTo)) {
+		upTo = resumed
+	}
+	if !upTo.IsZero() {
+		query.Set("before", upTo.UTC().Format(time.RFC3339))
+	}
+	return query
+}
+
+// resumeBefore is the state to resume from when a run is interrupted after
+// handling the given notification and all newer ones. The before query
+// parameter is exclusive, so it's placed just after that notification, which
+// only comes up again if it was kept.
+func resumeBefore(first Notification) state {
+	updated, err := time.Parse(time.RFC3339, first.UpdatedAt)
+	if err != nil {
+		return state{}
+	}
+	return state{Before: updated.Add(time.Second).Format(time.RFC3339)}
+}
+
+var linkRE = regexp.MustCompile(`<([^>]+)>;\s*rel="([^"]+)"`)
+
+func findNextPage(response *http.Response) (string, bool) {
+	return findLink(response, "next")
+}
+
+func findLink(response *http.Response, rel string) (string, bool) {
+	for _, m := range linkRE.FindAllStringSubmatch(response.Header.Get("Link"), -1) {
+		if len(m) > 2 && m[2] == rel {
+			return m[1], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestFindNextPage(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+		ok   bool
+	}{
+		{"next and last", `<https://api.github.com/notifications?page=2>; rel="next", <https://api.github.com/notifications?page=5>; rel="last"`, "https://api.github.com/notifications?page=2", true},
+		{"last page", `<https://api.github.com/notifications?page=1>; rel="prev", <https://api.github.com/notifications?page=1>; rel="first"`, "", false},
+		{"no link", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &http.Response{Header: http.Header{}}
+			if tt.link != "" {
+				response.Header.Set("Link", tt.link)
+			}
+			got, ok := findNextPage(response)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("findNextPage = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestReadStreakHaltsAcrossPages(t *testing.T) {
+	set(t, &haltAfter, 3)
+	set(t, &allPages, false)
+	resetRun()
+
+	wasRead := func(id string) Notification {
+		n := notification(id, "Issue", issueURL)
+		n.Unread = false
+		return n
+	}
+	unread := func(id string) Notification { return notification(id, "Issue", issueURL) }
+	client := newFakeClient().
+		on(http.MethodGet, "notifications", fakeResponse{body: page(t, wasRead("1"), unread("2"), wasRead("3"), wasRead("4")), link: `<page2>; rel="next"`}).
+		on(http.MethodGet, "page2", fakeResponse{body: page(t, wasRead("5"), unread("6")), link: `<page3>; rel="next"`}).
+		on(http.MethodGet, "page3", fakeResponse{body: page(t, unread("7"))})
+
+	var got []string
+	eachNotification(context.Background(), client, "notifications", func(n Notification) bool {
+		got = append(got, n.Id)
+		return true
+	})
+
+	want := []string{"1", "2", "3", "4", "5"}
+	if len(got) != len(want) {
+		t.Fatalf("streamed %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("streamed %v, want %v", got, want)
+		}
+	}
+	if !streamCompleted.Load() {
+		t.Error("halting on a streak doesn't count as completed")
+	}
+	if n := client.called(http.MethodGet, "page3"); n != 0 {
+		t.Errorf("fetched page 3 %d times after halting on page 2", n)
+	}
+}
+
+func TestUnreadResetsReadStreak(t *testing.T) {
+	set(t, &haltAfter, 2)
+	set(t, &allPages, false)
+	streak := newReadStreak()
+	for i, unread := range []bool{false, true, false} {
+		if streak.halts(Notification{Unread: unread}) {
+			t.Fatalf("halted after notification %d", i+1)
+		}
+	}
+	if !streak.halts(Notification{}) {
+		t.Error("didn't halt after two read notifications in a row")
+	}
+}
+
+func TestCountNotifications(t *testing.T) {
+	client := newFakeClient().
+		on(http.MethodGet, "notifications?per_page=1", fakeResponse{body: "[{}]", link: `<notifications?per_page=1&page=250>; rel="last"`})
+	tests := []struct {
+		name      string
+		haltAfter int
+		allPages  bool
+		maxPages  int
+		want      int
+		ok        bool
+	}{
+		{"whole inbox", 0, false, 0, 250, true},
+		{"capped by --max-pages", 0, false, 2, 200, true},
+		{"more pages than the inbox", 0, false, 5, 250, true},
+		{"--halt-after can stop early", 50, false, 0, 0, false},
+		{"--all-pages ignores --halt-after", 50, true, 0, 250, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set(t, &haltAfter, tt.haltAfter)
+			set(t, &allPages, tt.allPages)
+			set(t, &maxPages, tt.maxPages)
+			set(t, &perPage, 100)
+			got, ok := countNotifications(context.Background(), client, url.Values{})
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("countNotifications = %d, %v, want %d, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// wantedSubject is the --subject-url as a path like the one subjectPath
+// returns, empty when not given
+var wantedSubject string
+
+// subjectPath turns the API or web URL of a pull request or issue into its
+// API path without the host, e.g. repos/cli/cli/pulls/123, so URLs of the
+// same subject compare equal however they were written
+func subjectPath(raw string) (string, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	// GitHub Enterprise Server serves the API below /api/v3
+	if len(segments) > 2 && segments[0] == "api" && segments[1] == "v3" {
+		segments = segments[2:]
+	}
+	if len(segments) > 0 && segments[0] == "repos" {
+		segments = segments[1:]
+	} else if len(segments) > 4 {
+		// web URLs go on with tabs like /files or /commits
+		segments = segments[:4]
+	}
+	if len(segments) == 4 && segments[2] == "pull" {
+		segments[2] = "pulls"
+	}
+	if len(segments) != 4 || (segments[2] != "pulls" && segments[2] != "issues") {
+		return "", fmt.Errorf("%s is not the URL of a pull request or issue", raw)
+	}
+	if _, err := strconv.Atoi(segments[3]); err != nil {
+		return "", fmt.Errorf("%s is not the URL of a pull request or issue", raw)
+	}
+	return strings.ToLower("repos/" + strings.Join(segments, "/")), nil
+}
+
+// webURL guesses the page of a subject from its API URL, e.g.
+// https://github.com/cli/cli/pull/123 for a pull request, or is empty for
+// subjects without an obvious page like releases
+func webURL(apiURL string) string {
+	parsed, err := url.Parse(apiURL)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	// GitHub Enterprise Server serves the API below /api/v3
+	if len(segments) > 2 && segments[0] == "api" && segments[1] == "v3" {
+		segments = segments[2:]
+	}
+	if len(segments) != 5 || segments[0] != "repos" {
+		return ""
+	}
+	switch segments[3] {
+	case "pulls":
+		segments[3] = "pull"
+	case "commits":
+		segments[3] = "commit"
+	case "issues":
+	default:
+		return ""
+	}
+	host := strings.TrimPrefix(parsed.Host, "api.")
+	return parsed.Scheme + "://" + host + "/" + strings.Join(segments[1:], "/")
+}
+
+// displayURL is the URL people can click through, the API URL when there's
+// no page to go to
+func displayURL(notification Notification) string {
+	if notification.Subject.HtmlUrl != "" {
+		return notification.Subject.HtmlUrl
+	}
+	return notification.Subject.Url
+}
+
+func matchesSubject(notification Notification) bool {
+	if wantedSubject == "" {
+		return true
+	}
+	path, err := subjectPath(notification.Subject.Url)
+	return err == nil && path == wantedSubject
+}
+
+// notificationsPath lists only the notifications of the repository of
+// --subject-url when given, rather than all of them
+func notificationsPath() string {
+	if wantedSubject == "" {
+		return "notifications"
+	}
+	segments := strings.Split(wantedSubject, "/")
+	return strings.Join(segments[:3], "/") + "/notifications"
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// tally counts notifications by the markers that applied to them
+type tally struct {
+	Total       int `json:"total"`
+	Bot         int `json:"bot"`
+	Closed      int `json:"closed"`
+	Merged      int `json:"merged"`
+	ClosedIssue int `json:"closed_issue"`
+	Read        int `json:"read"`
+}
+
+func (t *tally) add(result NotificationResult) {
+	t.Total++
+	if result.BotPR || result.CI {
+		t.Bot++
+	}
+	if result.ClosedPR {
+		t.Closed++
+	}
+	if result.MergedPR {
+		t.Merged++
+	}
+	if result.ClosedIssue {
+		t.ClosedIssue++
+	}
+	if result.Read {
+		t.Read++
+	}
+}
+
+func (t tally) String() string {
+	return fmt.Sprintf("%d (bot: %d, closed: %d, merged: %d, closed issue: %d, read: %d)", t.Total, t.Bot, t.Closed, t.Merged, t.ClosedIssue, t.Read)
+}
+
+// summary accumulates the outcome of a run as results stream by
+type summary struct {
+	Type         string `json:"type"`
+	Seen         int    `json:"seen"`
+	Deleted      tally  `json:"deleted"`
+	MarkedRead   tally  `json:"marked_read"`
+	Unsubscribed int    `json:"unsubscribed"`
+	Skipped      tally  `json:"skipped"`
+	Excluded     int    `json:"excluded"`
+	Recent       int    `json:"recent"`
+	Protected    int    `json:"protected"`
+	OverLimit    int    `json:"over_limit"`
+	Errors       int    `json:"errors"`
+	Malformed    int    `json:"malformed"`
+	RateLimited  int    `json:"rate_limited"`
+	CacheHits    int    `json:"cache_hits"`
+	CacheMisses  int    `json:"cache_misses"`
+	Truncated    bool   `json:"truncated"`
+	Stats        *stats `json:"stats,omitempty"`
+
+	// failed are the results with an error, for printErrors
+	failed []NotificationResult
+	// unreadLeft counts the unread notifications kept as they were
+	unreadLeft int
+}
+
+// Exit codes of a run
+const (
+	exitOK          = 0
+	exitFailed      = 1
+	exitRateLimited = 3
+	exitPending     = 4
+	exitInterrupted = 130
+)
+
+func newSummary() *summary {
+	s := &summary{Type: "summary"}
+	if showStats {
+		s.Stats = newStats()
+	}
+	return s
+}
+
+func (s *summary) add(result NotificationResult) {
+	s.Seen++
+	if s.Stats != nil {
+		s.Stats.add(result.Notification, time.Now())
+	}
+	if result.Unsubscribed {
+		s.Unsubscribed++
+	}
+	switch {
+	case result.Err != nil:
+		s.Errors++
+		s.failed = append(s.failed, result)
+		if isRateLimited(result.Err) {
+			s.RateLimited++
+		}
+	case result.Deleted:
+		s.Deleted.add(result)
+	case result.MarkedRead:
+		s.MarkedRead.add(result)
+	default:
+		s.Skipped.add(result)
+		if result.Notification.Unread {
+			s.unreadLeft++
+		}
+		if result.Excluded {
+			s.Excluded++
+		}
+		if result.Recent {
+			s.Recent++
+		}
+		if result.Protected {
+			s.Protected++
+		}
+		if result.OverLimit {
+			s.OverLimit++
+		}
+	}
+}
+
+// finish adds the pages that couldn't be fetched once streaming is over
+func (s *summary) finish() {
+	s.Errors += int(pageErrors.Load())
+	s.RateLimited += int(rateLimitedPages.Load())
+	s.Malformed = int(malformed.Load())
+	s.CacheHits = int(subjects.hits.Load())
+	s.CacheMisses = int(subjects.misses.Load())
+	s.Truncated = truncated.Load()
+}
+
+// exitCode tells apart runs that went fine, runs where anything failed, and
+// runs cut short by the rate limit
+func (s *summary) exitCode() int {
+	switch {
+	case s.RateLimited > 0:
+		return exitRateLimited
+	case s.Errors > 0 || s.Malformed > 0:
+		return exitFailed
+	case exitIfPending && s.Deleted.Total+s.MarkedRead.Total > 0:
+		return exitPending
+	}
+	return exitOK
+}
+
+func (s *summary) print() {
+	fmt.Printf("Seen: %d\n", s.Seen)
+	fmt.Printf("Deleted: %s\n", s.Deleted)
+	if markRead {
+		fmt.Printf("Marked read: %s\n", s.MarkedRead)
+	}
+	if unsubscribe {
+		fmt.Printf("Unsubscribed: %d\n", s.Unsubscribed)
+	}
+	fmt.Printf("Skipped: %s\n", s.Skipped)
+	if s.Excluded > 0 {
+		fmt.Printf("Excluded: %d\n", s.Excluded)
+	}
+	if s.Recent > 0 {
+		fmt.Printf("Protected as recent: %d\n", s.Recent)
+	}
+	if s.Protected > 0 {
+		fmt.Printf("Protected by --never-delete-reasons/--protect-labels: %d\n", s.Protected)
+	}
+	if s.OverLimit > 0 {
+		fmt.Printf("Over --limit: %d\n", s.OverLimit)
+	}
+	fmt.Printf("Errors: %d\n", s.Errors)
+	if s.Malformed > 0 {
+		fmt.Printf("Malformed, skipped: %d\n", s.Malformed)
+	}
+	if s.RateLimited > 0 {
+		fmt.Printf("Rate limited: %d\n", s.RateLimited)
+	}
+	fmt.Printf("Cache: %d hits, %d misses\n", s.CacheHits, s.CacheMisses)
+	if s.Truncated {
+		fmt.Println("Stopped after --max-pages, older notifications are left")
+	}
+	if s.Stats != nil {
+		s.Stats.print()
+	}
+}
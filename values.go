@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// durationValue is a flag value holding a time.Duration that, on top of the
+// units understood by time.ParseDuration, accepts whole days such as 30d
+type durationValue time.Duration
+
+func (d *durationValue) Set(value string) error {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return fmt.Errorf("invalid number of days %q", value)
+		}
+		*d = durationValue(time.Duration(n) * 24 * time.Hour)
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = durationValue(parsed)
+	return nil
+}
+
+func (d *durationValue) String() string {
+	if *d == 0 {
+		return "0"
+	}
+	return time.Duration(*d).String()
+}
+
+func (d *durationValue) Type() string {
+	return "duration"
+}
+
+// regexpValue is a flag value holding a regular expression, which is compiled
+// while parsing flags so an invalid pattern is reported right away
+type regexpValue struct {
+	*regexp.Regexp
+}
+
+func (r *regexpValue) Set(value string) error {
+	compiled, err := regexp.Compile(value)
+	if err != nil {
+		return err
+	}
+	r.Regexp = compiled
+	return nil
+}
+
+func (r *regexpValue) String() string {
+	if r.Regexp == nil {
+		return ""
+	}
+	return r.Regexp.String()
+}
+
+func (r *regexpValue) Type() string {
+	return "regexp"
+}
+
+// templateValue is a flag value holding a text/template, parsed while parsing
+// flags so a broken template is reported before any request is made
+type templateValue struct {
+	*template.Template
+	text string
+}
+
+func (t *templateValue) Set(value string) error {
+	parsed, err := template.New("row").Parse(value)
+	if err != nil {
+		return err
+	}
+	t.Template, t.text = parsed, value
+	return nil
+}
+
+func (t *templateValue) String() string {
+	return t.text
+}
+
+func (t *templateValue) Type() string {
+	return "template"
+}
+
+// timeValue is a flag value holding a point in time, given either as a date
+// like 2024-01-01, which means midnight local time, or as an RFC3339 time
+type timeValue struct {
+	time.Time
+}
+
+func (t *timeValue) Set(value string) error {
+	if parsed, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		t.Time = parsed
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return fmt.Errorf("expected a date like 2024-01-01 or an RFC3339 time, got %q", value)
+	}
+	t.Time = parsed
+	return nil
+}
+
+func (t *timeValue) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func (t *timeValue) Type() string {
+	return "date"
+}
+
+// levelValue is a flag value holding a log level such as error, info or debug
+type levelValue struct {
+	slog.Level
+}
+
+func (l *levelValue) Set(value string) error {
+	return l.UnmarshalText([]byte(value))
+}
+
+func (l *levelValue) String() string {
+	return strings.ToLower(l.Level.String())
+}
+
+func (l *levelValue) Type() string {
+	return "level"
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// watchNotifications runs the pipeline every --interval until interrupted and
+// returns the exit code of the last run. An interrupt lets the current run
+// finish, only a second one kills it on the spot.
+func watchNotifications(ctx context.Context, stop context.CancelFunc, client restClient, query url.Values) int {
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	exitCode := exitOK
+	for {
+		resetRun()
+		totals := run(context.Background(), client, query)
+		exitCode = totals.exitCode()
+		if tableOutput() {
+			fmt.Printf("Done 🎉 next run at %s\n", time.Now().Add(watchInterval).Format(time.TimeOnly))
+			totals.print()
+		}
+		// --resume only picks up where the run before this process stopped
+		query = notificationsQuery(state{})
+
+		select {
+		case <-ctx.Done():
+			closeOutput(totals)
+			audit.close()
+			dump.close()
+			if tableOutput() {
+				fmt.Println("Stopped watching 🛑")
+			}
+			return exitCode
+		case <-time.After(watchInterval):
+		}
+	}
+}
+
+// resetRun forgets everything the previous run counted and cached, subjects
+// included since pull requests and issues change state in the meantime
+func resetRun() {
+	subjects = subjectCache{}
+	counters.fetched.Store(0)
+	counters.tagged.Store(0)
+	counters.deleted.Store(0)
+	counters.total.Store(0)
+	deletions.Store(0)
+	pageErrors.Store(0)
+	rateLimitedPages.Store(0)
+	malformed.Store(0)
+	truncated.Store(false)
+	streamCompleted.Store(false)
+	checkpoints = newCheckpoint()
+}